@@ -1,6 +1,8 @@
 package main
 
 import (
+	"strings"
+
 	"github.com/christophwitzko/npm-binary-releaser/pkg/config"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -15,10 +17,12 @@ func must(err error) {
 func SetFlags(cmd *cobra.Command) {
 	envInfo := config.GetRepositoryAndHomepageFromEnv()
 	cmd.PersistentFlags().StringP("input-path", "i", "", "input path that contains the binary files [uses ./bin or ./dist as default]")
+	cmd.PersistentFlags().StringSlice("binaries", nil, "binaries to include in every package as name=pattern, the pattern matches the input file names (e.g. my-cool-cli=my-cool-cli_*,my-daemon=my-daemon_*)")
 	cmd.PersistentFlags().StringP("output-path", "o", config.DefaultOutputDirPath, "output directory")
 	cmd.PersistentFlags().StringP("name", "n", envInfo.Name, "name of the binary (e.g my-cool-cli)")
 	cmd.PersistentFlags().StringP("package-name-prefix", "p", "", "package name prefix for all created packages (e.g. @my-org/)")
-	cmd.PersistentFlags().StringP("package-version", "r", "", "version of the created packages")
+	cmd.PersistentFlags().StringP("package-version", "r", "", "semantic version of the created packages [defaults to the GoReleaser metadata, GITHUB_REF_NAME or the current git tag]")
+	cmd.PersistentFlags().String("dist-tag", "", "npm dist-tag for the published packages [defaults to latest, or the prerelease identifier for prerelease versions] (e.g. beta)")
 	cmd.PersistentFlags().String("package-name", "", "package name [defaults to the name of the binary] (e.g. my-cool-cli)")
 	cmd.PersistentFlags().String("license", "", "package SPDX license (e.g. MIT)")
 	cmd.PersistentFlags().String("homepage", envInfo.Homepage, "package homepage")
@@ -26,7 +30,26 @@ func SetFlags(cmd *cobra.Command) {
 	cmd.PersistentFlags().String("repository", envInfo.Repository, "package repository")
 	cmd.PersistentFlags().String("readme-path", config.DefaultReadmePath, "README file to include in generated packages")
 	cmd.PersistentFlags().String("publish-registry", config.DefaultPublishRegistry, "npm registry endpoint")
-	cmd.PersistentFlags().Bool("publish", false, "run npm publish for all packages")
+	cmd.PersistentFlags().Bool("goreleaser-artifacts", false, "read the binaries and the version from GoReleaser's artifacts.json and metadata.json in the input path [uses ./dist as default]")
+	cmd.PersistentFlags().String("archive-binary-path", "", "glob pattern of the binary inside of input archives [defaults to the name of the binary] (e.g. */my-cool-cli)")
+	cmd.PersistentFlags().StringSlice("archive-extra-files", nil, "glob patterns of additional archive files to include in the packages, relative to the binary (e.g. LICENSE,completions/*)")
+	cmd.PersistentFlags().StringSlice("include-platforms", nil, "glob patterns of the platforms to release, matched against os-cpu and os-cpu-libc, Go names are converted (e.g. linux-*,windows-amd64)")
+	cmd.PersistentFlags().StringSlice("exclude-platforms", nil, "glob patterns of the platforms to skip (e.g. plan9-*,*-mips*)")
+	cmd.PersistentFlags().StringSlice("required-platforms", nil, "fail if no binary is found for one of these platforms (e.g. linux-x64,darwin-arm64,win32-x64)")
+	cmd.PersistentFlags().StringSlice("prefer-sources", nil, "glob patterns of input file names that win if multiple files match the same platform, in order of preference (e.g. *_amd64*,*.tar.gz)")
+	cmd.PersistentFlags().String("checksums-file", "", "verify the input files and archives against this checksums file before they are used (e.g. dist/my-cool-cli_checksums.txt)")
+	cmd.PersistentFlags().String("variant-policy", config.DefaultVariantPolicy, "variant (GOARM/GOAMD64) to release if there are multiple for the same os/cpu: lowest (most compatible), highest or error")
+	cmd.PersistentFlags().Bool("fail-on-platform-mismatch", false, "fail if the os/arch in a file name does not match the binary header")
+	cmd.PersistentFlags().String("binary-path-env", "", "environment variable that overrides the binary used by the launcher [defaults to <NAME>_BINARY_PATH] (e.g. MY_COOL_CLI_BINARY_PATH)")
+	cmd.PersistentFlags().Bool("install-fallback", false, "add a postinstall script that downloads the platform package if it was not installed (e.g. --omit=optional)")
+	cmd.PersistentFlags().Bool("direct-bin-link", false, "add a postinstall script that replaces run.js with a link to the binary on Unix to skip the Node.js launcher (npm only)")
+	cmd.PersistentFlags().Bool("pack", false, "create reproducible npm tarballs (.tgz) of all packages in the output directory")
+	cmd.PersistentFlags().Bool("publish", false, "publish all packages to the npm registry")
+	cmd.PersistentFlags().Int("publish-concurrency", config.DefaultPublishConcurrency, "maximum number of platform packages that are published in parallel")
+	cmd.PersistentFlags().Int("publish-retries", config.DefaultPublishRetries, "number of retries for publishes that failed with a retryable error (5xx, 429, network errors)")
+	cmd.PersistentFlags().Duration("publish-retry-delay", config.DefaultPublishRetryDelay, "initial delay between publish retries, doubled after every retry")
+	cmd.PersistentFlags().Duration("publish-retry-max-delay", config.DefaultPublishRetryMaxDelay, "maximum delay between publish retries")
+	cmd.PersistentFlags().Bool("publish-with-npm", false, "use the npm CLI (npm publish) instead of the built-in registry client")
 	cmd.PersistentFlags().Bool("no-prefix-for-main-package", false, "ignore the configured package name prefix for the main package")
 	cmd.PersistentFlags().SortFlags = true
 
@@ -34,6 +57,7 @@ func SetFlags(cmd *cobra.Command) {
 	must(viper.BindPFlag("outputPath", cmd.PersistentFlags().Lookup("output-path")))
 	must(viper.BindPFlag("name", cmd.PersistentFlags().Lookup("name")))
 	must(viper.BindPFlag("packageNamePrefix", cmd.PersistentFlags().Lookup("package-name-prefix")))
+	must(viper.BindPFlag("distTag", cmd.PersistentFlags().Lookup("dist-tag")))
 	must(viper.BindPFlag("packageName", cmd.PersistentFlags().Lookup("package-name")))
 	must(viper.BindPFlag("license", cmd.PersistentFlags().Lookup("license")))
 	must(viper.BindPFlag("homepage", cmd.PersistentFlags().Lookup("homepage")))
@@ -41,20 +65,47 @@ func SetFlags(cmd *cobra.Command) {
 	must(viper.BindPFlag("repository", cmd.PersistentFlags().Lookup("repository")))
 	must(viper.BindPFlag("readmePath", cmd.PersistentFlags().Lookup("readme-path")))
 	must(viper.BindPFlag("publishRegistry", cmd.PersistentFlags().Lookup("publish-registry")))
+	must(viper.BindPFlag("goreleaserArtifacts", cmd.PersistentFlags().Lookup("goreleaser-artifacts")))
+	must(viper.BindPFlag("archiveBinaryPath", cmd.PersistentFlags().Lookup("archive-binary-path")))
+	must(viper.BindPFlag("archiveExtraFiles", cmd.PersistentFlags().Lookup("archive-extra-files")))
+	must(viper.BindPFlag("includePlatforms", cmd.PersistentFlags().Lookup("include-platforms")))
+	must(viper.BindPFlag("excludePlatforms", cmd.PersistentFlags().Lookup("exclude-platforms")))
+	must(viper.BindPFlag("requiredPlatforms", cmd.PersistentFlags().Lookup("required-platforms")))
+	must(viper.BindPFlag("preferSources", cmd.PersistentFlags().Lookup("prefer-sources")))
+	must(viper.BindPFlag("checksumsFile", cmd.PersistentFlags().Lookup("checksums-file")))
+	must(viper.BindPFlag("variantPolicy", cmd.PersistentFlags().Lookup("variant-policy")))
+	must(viper.BindPFlag("failOnPlatformMismatch", cmd.PersistentFlags().Lookup("fail-on-platform-mismatch")))
+	must(viper.BindPFlag("binaryPathEnv", cmd.PersistentFlags().Lookup("binary-path-env")))
+	must(viper.BindPFlag("installFallback", cmd.PersistentFlags().Lookup("install-fallback")))
+	must(viper.BindPFlag("directBinLink", cmd.PersistentFlags().Lookup("direct-bin-link")))
+	must(viper.BindPFlag("pack", cmd.PersistentFlags().Lookup("pack")))
 	must(viper.BindPFlag("publish", cmd.PersistentFlags().Lookup("publish")))
+	must(viper.BindPFlag("publishConcurrency", cmd.PersistentFlags().Lookup("publish-concurrency")))
+	must(viper.BindPFlag("publishRetries", cmd.PersistentFlags().Lookup("publish-retries")))
+	must(viper.BindPFlag("publishRetryDelay", cmd.PersistentFlags().Lookup("publish-retry-delay")))
+	must(viper.BindPFlag("publishRetryMaxDelay", cmd.PersistentFlags().Lookup("publish-retry-max-delay")))
+	must(viper.BindPFlag("publishWithNpm", cmd.PersistentFlags().Lookup("publish-with-npm")))
 	must(viper.BindPFlag("noPrefixForMainPackage", cmd.PersistentFlags().Lookup("no-prefix-for-main-package")))
 }
 
 func NewConfig(cmd *cobra.Command) *config.Config {
 	packageVersion, err := cmd.Flags().GetString("package-version")
 	must(err)
+	binaries, err := parseBinaries(cmd)
+	must(err)
+	// the explicit platform mapping is only supported in the config file
+	var platforms []config.Platform
+	must(viper.UnmarshalKey("platforms", &platforms))
 	c := &config.Config{
+		Binaries:               binaries,
+		Platforms:              platforms,
 		InputBinDirPath:        viper.GetString("inputPath"),
 		TryDefaultInputPaths:   !viper.IsSet("inputPath"),
 		OutputDirPath:          viper.GetString("outputPath"),
 		BinName:                viper.GetString("name"),
 		PackageNamePrefix:      viper.GetString("packageNamePrefix"),
 		PackageVersion:         packageVersion,
+		DistTag:                viper.GetString("distTag"),
 		PackageName:            viper.GetString("packageName"),
 		License:                viper.GetString("license"),
 		Homepage:               viper.GetString("homepage"),
@@ -62,12 +113,50 @@ func NewConfig(cmd *cobra.Command) *config.Config {
 		Repository:             viper.GetString("repository"),
 		ReadmePath:             viper.GetString("readmePath"),
 		PublishRegistry:        viper.GetString("publishRegistry"),
+		GoReleaserArtifacts:    viper.GetBool("goreleaserArtifacts"),
+		ArchiveBinaryPath:      viper.GetString("archiveBinaryPath"),
+		ArchiveExtraFiles:      viper.GetStringSlice("archiveExtraFiles"),
+		FailOnPlatformMismatch: viper.GetBool("failOnPlatformMismatch"),
+		VariantPolicy:          viper.GetString("variantPolicy"),
+		IncludePlatforms:       viper.GetStringSlice("includePlatforms"),
+		ExcludePlatforms:       viper.GetStringSlice("excludePlatforms"),
+		RequiredPlatforms:      viper.GetStringSlice("requiredPlatforms"),
+		PreferSources:          viper.GetStringSlice("preferSources"),
+		ChecksumsFile:          viper.GetString("checksumsFile"),
+		BinaryPathEnv:          viper.GetString("binaryPathEnv"),
+		InstallFallback:        viper.GetBool("installFallback"),
+		DirectBinLink:          viper.GetBool("directBinLink"),
+		Pack:                   viper.GetBool("pack"),
 		Publish:                viper.GetBool("publish"),
+		PublishWithNpm:         viper.GetBool("publishWithNpm"),
+		PublishConcurrency:     viper.GetInt("publishConcurrency"),
+		PublishRetries:         viper.GetInt("publishRetries"),
+		PublishRetryDelay:      viper.GetDuration("publishRetryDelay"),
+		PublishRetryMaxDelay:   viper.GetDuration("publishRetryMaxDelay"),
 		NoPrefixForMainPackage: viper.GetBool("noPrefixForMainPackage"),
 	}
 	return c
 }
 
+// parseBinaries reads the binaries from the --binaries flag or the config file.
+func parseBinaries(cmd *cobra.Command) ([]config.Binary, error) {
+	if !cmd.Flags().Changed("binaries") {
+		var binaries []config.Binary
+		err := viper.UnmarshalKey("binaries", &binaries)
+		return binaries, err
+	}
+	values, err := cmd.Flags().GetStringSlice("binaries")
+	if err != nil {
+		return nil, err
+	}
+	binaries := make([]config.Binary, 0, len(values))
+	for _, value := range values {
+		name, match, _ := strings.Cut(value, "=")
+		binaries = append(binaries, config.Binary{Name: name, Match: match})
+	}
+	return binaries, nil
+}
+
 func InitConfig() error {
 	viper.AddConfigPath(".")
 	viper.SetConfigName(".npm-binary-releaser.yaml")
@@ -1,10 +1,13 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"log"
 	"os"
+	"text/tabwriter"
 
+	"github.com/christophwitzko/npm-binary-releaser/pkg/config"
 	"github.com/christophwitzko/npm-binary-releaser/pkg/releaser"
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v3"
@@ -43,6 +46,36 @@ func main() {
 	configCmd.Flags().Bool("validate", false, "validate the config")
 	cmd.AddCommand(configCmd)
 
+	planCmd := &cobra.Command{
+		Use:   "plan",
+		Short: "Print the packages that would be generated and published without writing anything",
+		Run:   planHandler,
+	}
+	planCmd.Flags().Bool("json", false, "print the plan as JSON")
+	cmd.AddCommand(planCmd)
+
+	distTagCmd := &cobra.Command{
+		Use:   "dist-tag",
+		Short: "Manage dist-tags of the main package and all platform packages",
+	}
+	distTagCmd.AddCommand(&cobra.Command{
+		Use:   "add <tag>",
+		Short: "Point the dist-tag of all packages to the package version",
+		Args:  cobra.ExactArgs(1),
+		Run: distTagHandler(func(c *config.Config, logger *log.Logger, tag string) error {
+			return releaser.SetDistTag(c, logger, tag)
+		}),
+	})
+	distTagCmd.AddCommand(&cobra.Command{
+		Use:   "rm <tag>",
+		Short: "Remove the dist-tag from all packages",
+		Args:  cobra.ExactArgs(1),
+		Run: distTagHandler(func(c *config.Config, logger *log.Logger, tag string) error {
+			return releaser.RemoveDistTag(c, logger, tag)
+		}),
+	})
+	cmd.AddCommand(distTagCmd)
+
 	cobra.OnInitialize(func() {
 		if err := InitConfig(); err != nil {
 			fmt.Printf("\nConfig error: %s\n", err.Error())
@@ -64,3 +97,55 @@ func cliHandler(cmd *cobra.Command, args []string) {
 		return
 	}
 }
+
+func planHandler(cmd *cobra.Command, args []string) {
+	var logger = log.New(os.Stderr, "[npm-binary-releaser]: ", 0)
+	plan, err := releaser.CreatePlan(NewConfig(cmd), logger)
+	if err != nil {
+		logger.Println(err)
+		os.Exit(1)
+		return
+	}
+
+	if printJSON, _ := cmd.Flags().GetBool("json"); printJSON {
+		planStr, _ := json.MarshalIndent(plan, "", "  ")
+		fmt.Println(string(planStr))
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "ORDER\tPACKAGE\tVERSION\tOS\tCPU\tLIBC\tSOURCE\tFILE")
+	for _, pkg := range plan.Packages {
+		if len(pkg.Binaries) == 0 {
+			_, _ = fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", pkg.PublishOrder, pkg.Name, pkg.Version,
+				orDash(pkg.OS), orDash(pkg.CPU), orDash(pkg.Libc), orDash(pkg.Source), pkg.BinFileName)
+			continue
+		}
+		// one line per binary, the package columns are only printed once
+		for i, binary := range pkg.Binaries {
+			if i > 0 {
+				_, _ = fmt.Fprintf(w, "\t\t\t\t\t\t%s\t%s\n", binary.Source, binary.BinFileName)
+				continue
+			}
+			_, _ = fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", pkg.PublishOrder, pkg.Name, pkg.Version,
+				orDash(pkg.OS), orDash(pkg.CPU), orDash(pkg.Libc), binary.Source, binary.BinFileName)
+		}
+	}
+	_ = w.Flush()
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func distTagHandler(handler func(c *config.Config, logger *log.Logger, tag string) error) func(cmd *cobra.Command, args []string) {
+	return func(cmd *cobra.Command, args []string) {
+		var logger = log.New(os.Stderr, "[npm-binary-releaser]: ", 0)
+		if err := handler(NewConfig(cmd), logger, args[0]); err != nil {
+			logger.Println(err)
+			os.Exit(1)
+		}
+	}
+}
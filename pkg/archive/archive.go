@@ -0,0 +1,159 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ulikunitz/xz"
+)
+
+var supportedExtensions = []string{".tar.gz", ".tgz", ".tar.xz", ".txz", ".zip"}
+
+// IsArchive reports whether the file name has the extension of a supported archive format.
+func IsArchive(fileName string) bool {
+	fileName = strings.ToLower(fileName)
+	for _, ext := range supportedExtensions {
+		if strings.HasSuffix(fileName, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// Extract extracts all regular files and directories of the archive into destDir.
+func Extract(archivePath, destDir string) error {
+	lowerName := strings.ToLower(archivePath)
+	if strings.HasSuffix(lowerName, ".zip") {
+		return extractZip(archivePath, destDir)
+	}
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var reader io.Reader
+	switch {
+	case strings.HasSuffix(lowerName, ".tar.gz"), strings.HasSuffix(lowerName, ".tgz"):
+		gzReader, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer gzReader.Close()
+		reader = gzReader
+	case strings.HasSuffix(lowerName, ".tar.xz"), strings.HasSuffix(lowerName, ".txz"):
+		xzReader, err := xz.NewReader(f)
+		if err != nil {
+			return err
+		}
+		reader = xzReader
+	default:
+		return fmt.Errorf("unsupported archive format: %s", archivePath)
+	}
+	return extractTar(reader, destDir)
+}
+
+// targetPath returns the path of an archive entry inside destDir and prevents path traversal.
+func targetPath(destDir, name string) (string, error) {
+	target := filepath.Join(destDir, filepath.FromSlash(name))
+	relPath, err := filepath.Rel(destDir, target)
+	if err != nil {
+		return "", err
+	}
+	if relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid archive entry: %s", name)
+	}
+	return target, nil
+}
+
+func extractTar(r io.Reader, destDir string) error {
+	tarReader := tar.NewReader(r)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		target, err := targetPath(destDir, header.Name)
+		if err != nil {
+			return err
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := writeFile(target, tarReader, header.FileInfo().Mode()); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func extractZip(archivePath, destDir string) error {
+	zipReader, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return err
+	}
+	defer zipReader.Close()
+	for _, file := range zipReader.File {
+		target, err := targetPath(destDir, file.Name)
+		if err != nil {
+			return err
+		}
+		mode := file.Mode()
+		if mode.IsDir() {
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+			continue
+		}
+		if !mode.IsRegular() {
+			continue
+		}
+		if err := extractZipFile(file, target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func extractZipFile(file *zip.File, target string) error {
+	reader, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	mode := file.Mode()
+	// zip archives created on Windows do not contain unix permissions
+	if strings.HasSuffix(strings.ToLower(file.Name), ".exe") {
+		mode |= 0755
+	}
+	return writeFile(target, reader, mode)
+}
+
+func writeFile(target string, r io.Reader, mode os.FileMode) (err error) {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode.Perm()|0600)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+	_, err = io.Copy(f, r)
+	return err
+}
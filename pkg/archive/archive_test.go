@@ -0,0 +1,116 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTarGz(t *testing.T, archivePath string, files map[string]string) {
+	t.Helper()
+	f, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gzWriter := gzip.NewWriter(f)
+	tarWriter := tar.NewWriter(gzWriter)
+	for name, content := range files {
+		if err := tarWriter.WriteHeader(&tar.Header{Name: name, Mode: 0755, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tarWriter.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tarWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gzWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeZip(t *testing.T, archivePath string, files map[string]string) {
+	t.Helper()
+	f, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zipWriter := zip.NewWriter(f)
+	for name, content := range files {
+		w, err := zipWriter.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zipWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func assertFileContent(t *testing.T, filePath, want string) {
+	t.Helper()
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != want {
+		t.Fatalf("%s = %q, want %q", filePath, string(data), want)
+	}
+}
+
+func TestExtract(t *testing.T) {
+	files := map[string]string{
+		"my-cli/my-cli":  "binary",
+		"my-cli/LICENSE": "MIT",
+	}
+	for _, archiveName := range []string{"my-cli_linux_amd64.tar.gz", "my-cli_windows_amd64.zip"} {
+		t.Run(archiveName, func(t *testing.T) {
+			archivePath := filepath.Join(t.TempDir(), archiveName)
+			if filepath.Ext(archiveName) == ".zip" {
+				writeZip(t, archivePath, files)
+			} else {
+				writeTarGz(t, archivePath, files)
+			}
+			if !IsArchive(archivePath) {
+				t.Fatalf("%s is not detected as archive", archiveName)
+			}
+			destDir := t.TempDir()
+			if err := Extract(archivePath, destDir); err != nil {
+				t.Fatal(err)
+			}
+			assertFileContent(t, filepath.Join(destDir, "my-cli", "my-cli"), "binary")
+			assertFileContent(t, filepath.Join(destDir, "my-cli", "LICENSE"), "MIT")
+		})
+	}
+}
+
+func TestExtractPreventsPathTraversal(t *testing.T) {
+	archivePath := filepath.Join(t.TempDir(), "evil.tar.gz")
+	writeTarGz(t, archivePath, map[string]string{"../evil": "x"})
+	if err := Extract(archivePath, t.TempDir()); err == nil {
+		t.Fatal("expected error for path traversal")
+	}
+}
+
+func TestIsArchive(t *testing.T) {
+	for fileName, want := range map[string]bool{
+		"my-cli.tar.gz": true,
+		"my-cli.TAR.XZ": true,
+		"my-cli.zip":    true,
+		"my-cli.exe":    false,
+		"my-cli":        false,
+	} {
+		if got := IsArchive(fileName); got != want {
+			t.Errorf("IsArchive(%q) = %v, want %v", fileName, got, want)
+		}
+	}
+}
@@ -4,46 +4,149 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"os/exec"
+	"path"
+	"regexp"
 	"strings"
+	"time"
+
+	"github.com/christophwitzko/npm-binary-releaser/pkg/goreleaser"
+	"github.com/christophwitzko/npm-binary-releaser/pkg/helper"
 )
 
+// Binary is an executable that is included in every platform package.
+type Binary struct {
+	// Name is the name of the bin entry in the main package.
+	Name string `yaml:"name"`
+	// Match is a glob pattern for the names of the binary files (or directories) in the input path
+	// (e.g. my-daemon_*). Inside of archives the binary is found by its name.
+	Match string `yaml:"match,omitempty"`
+}
+
+// Platform maps a platform package to the path of its binaries.
+type Platform struct {
+	// OS and CPU are the Node.js platform and arch (e.g. linux and x64), GOOS and GOARCH values are converted.
+	OS  string `yaml:"os"`
+	CPU string `yaml:"cpu"`
+	// Libc (glibc or musl) is only needed if both variants are released for the same Linux cpu.
+	Libc string `yaml:"libc,omitempty"`
+	// Path is a binary, a directory or an archive that contains the binaries.
+	Path string `yaml:"path"`
+}
+
+// Target returns the platform in the format of the platform package suffix (e.g. linux-x64-musl).
+func (p Platform) Target() string {
+	if p.Libc != "" {
+		return p.OS + "-" + p.CPU + "-" + p.Libc
+	}
+	return p.OS + "-" + p.CPU
+}
+
 type Config struct {
-	BinName                string `yaml:"name"`
-	InputBinDirPath        string `yaml:"inputPath,omitempty"`
-	TryDefaultInputPaths   bool   `yaml:"-"`
-	PackageName            string `yaml:"packageName"`
-	Description            string `yaml:"description"`
-	License                string `yaml:"license"`
-	Homepage               string `yaml:"homepage"`
-	Repository             string `yaml:"repository"`
-	PackageNamePrefix      string `yaml:"packageNamePrefix"`
-	NoPrefixForMainPackage bool   `yaml:"noPrefixForMainPackage"`
-	PackageVersion         string `yaml:"-"`
-	OutputDirPath          string `yaml:"outputPath"`
-	ReadmePath             string `yaml:"readmePath"`
-	PublishRegistry        string `yaml:"publishRegistry"`
-	Publish                bool   `yaml:"publish"`
+	BinName                string        `yaml:"name"`
+	Binaries               []Binary      `yaml:"binaries,omitempty"`
+	InputBinDirPath        string        `yaml:"inputPath,omitempty"`
+	TryDefaultInputPaths   bool          `yaml:"-"`
+	Platforms              []Platform    `yaml:"platforms,omitempty"`
+	IncludePlatforms       []string      `yaml:"includePlatforms,omitempty"`
+	ExcludePlatforms       []string      `yaml:"excludePlatforms,omitempty"`
+	RequiredPlatforms      []string      `yaml:"requiredPlatforms,omitempty"`
+	PreferSources          []string      `yaml:"preferSources,omitempty"`
+	GoReleaserArtifacts    bool          `yaml:"goreleaserArtifacts"`
+	PackageName            string        `yaml:"packageName"`
+	Description            string        `yaml:"description"`
+	License                string        `yaml:"license"`
+	Homepage               string        `yaml:"homepage"`
+	Repository             string        `yaml:"repository"`
+	PackageNamePrefix      string        `yaml:"packageNamePrefix"`
+	NoPrefixForMainPackage bool          `yaml:"noPrefixForMainPackage"`
+	PackageVersion         string        `yaml:"-"`
+	DistTag                string        `yaml:"distTag,omitempty"`
+	ArchiveBinaryPath      string        `yaml:"archiveBinaryPath,omitempty"`
+	ArchiveExtraFiles      []string      `yaml:"archiveExtraFiles,omitempty"`
+	FailOnPlatformMismatch bool          `yaml:"failOnPlatformMismatch"`
+	VariantPolicy          string        `yaml:"variantPolicy"`
+	ChecksumsFile          string        `yaml:"checksumsFile,omitempty"`
+	OutputDirPath          string        `yaml:"outputPath"`
+	ReadmePath             string        `yaml:"readmePath"`
+	BinaryPathEnv          string        `yaml:"binaryPathEnv,omitempty"`
+	InstallFallback        bool          `yaml:"installFallback"`
+	DirectBinLink          bool          `yaml:"directBinLink"`
+	Pack                   bool          `yaml:"pack"`
+	PublishRegistry        string        `yaml:"publishRegistry"`
+	Publish                bool          `yaml:"publish"`
+	PublishWithNpm         bool          `yaml:"publishWithNpm"`
+	PublishConcurrency     int           `yaml:"publishConcurrency"`
+	PublishRetries         int           `yaml:"publishRetries"`
+	PublishRetryDelay      time.Duration `yaml:"publishRetryDelay"`
+	PublishRetryMaxDelay   time.Duration `yaml:"publishRetryMaxDelay"`
 }
 
 var defaultInputDirPaths = []string{"./bin", "./dist"}
 
+const defaultGoReleaserDistPath = "./dist"
+
 const DefaultOutputDirPath = "./generated-packages"
 const DefaultReadmePath = "README.md"
 const DefaultPublishRegistry = "https://registry.npmjs.org/"
+const DefaultPublishConcurrency = 4
+const DefaultPublishRetries = 3
+const DefaultPublishRetryDelay = 2 * time.Second
+const DefaultPublishRetryMaxDelay = 30 * time.Second
+const DefaultDistTag = "latest"
+
+// Variant policies decide which variant (GOARM or GOAMD64) is released if there are multiple variants for the
+// same npm cpu value.
+const (
+	VariantPolicyLowest  = "lowest"
+	VariantPolicyHighest = "highest"
+	VariantPolicyError   = "error"
+)
+
+const DefaultVariantPolicy = VariantPolicyLowest
+const DefaultPrereleaseDistTag = "next"
+
+// envNameRegexp matches valid names of environment variables.
+var envNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// binNameRegexp matches valid names of bin entries, which are also used as file names.
+var binNameRegexp = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// distTagRegexp matches names that can be used as dist-tag, which must not be a number or a valid semver range.
+var distTagRegexp = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9-]*$`)
+
+// semverRangeTagRegexp matches the names that are allowed by distTagRegexp but are valid semver ranges (e.g. v1 or x).
+var semverRangeTagRegexp = regexp.MustCompile(`^v?([0-9]+|[xX])$`)
+
+// semverRegexp is the official semantic versioning 2.0.0 regular expression (see https://semver.org).
+var semverRegexp = regexp.MustCompile(`^(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)(?:-((?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*)(?:\.(?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*))*))?(?:\+([0-9a-zA-Z-]+(?:\.[0-9a-zA-Z-]+)*))?$`)
 
 func (c *Config) Validate() error {
+	if c.BinName == "" && len(c.Binaries) > 0 {
+		c.BinName = c.Binaries[0].Name
+	}
 	if c.PackageName == "" {
 		c.PackageName = c.BinName
 	}
-	if c.PackageVersion == "" {
-		return fmt.Errorf("package version is missing")
-	}
 	if c.BinName == "" {
 		return fmt.Errorf("name is missing")
 	}
+	if err := c.validateBinaries(); err != nil {
+		return err
+	}
+	if c.BinaryPathEnv == "" {
+		c.BinaryPathEnv = BinaryPathEnvForName(c.BinName)
+	}
+	if !envNameRegexp.MatchString(c.BinaryPathEnv) {
+		return fmt.Errorf("invalid binary path environment variable name: %s", c.BinaryPathEnv)
+	}
 	if c.TryDefaultInputPaths {
 		c.InputBinDirPath = ""
-		for _, dirPath := range defaultInputDirPaths {
+		inputDirPaths := defaultInputDirPaths
+		if c.GoReleaserArtifacts {
+			inputDirPaths = []string{defaultGoReleaserDistPath}
+		}
+		for _, dirPath := range inputDirPaths {
 			_, err := os.Stat(dirPath)
 			if err == nil {
 				c.InputBinDirPath = dirPath
@@ -63,9 +166,235 @@ func (c *Config) Validate() error {
 			}
 		}
 	}
-	if c.InputBinDirPath == "" {
+	if err := c.validatePlatforms(); err != nil {
+		return err
+	}
+	switch c.VariantPolicy {
+	case "":
+		c.VariantPolicy = DefaultVariantPolicy
+	case VariantPolicyLowest, VariantPolicyHighest, VariantPolicyError:
+	default:
+		return fmt.Errorf("invalid variant policy: %s (must be %s, %s or %s)", c.VariantPolicy, VariantPolicyLowest, VariantPolicyHighest, VariantPolicyError)
+	}
+	if err := c.validatePlatformFilters(); err != nil {
+		return err
+	}
+	if err := c.validatePublishOptions(); err != nil {
+		return err
+	}
+	if c.InputBinDirPath == "" && len(c.Platforms) == 0 {
 		return fmt.Errorf("input path is missing or does not exist")
 	}
+	if c.PackageVersion == "" && c.InputBinDirPath != "" {
+		metadata, err := goreleaser.ReadMetadata(c.InputBinDirPath)
+		if err == nil {
+			c.PackageVersion = metadata.Version
+		} else if c.GoReleaserArtifacts || !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("could not read GoReleaser metadata: %w", err)
+		}
+	}
+	if c.PackageVersion == "" {
+		c.PackageVersion = GetVersionFromEnv()
+	}
+	if c.PackageVersion == "" {
+		c.PackageVersion = GetVersionFromGitTag()
+	}
+	if c.PackageVersion == "" {
+		return fmt.Errorf("package version is missing")
+	}
+	version, err := NormalizeVersion(c.PackageVersion)
+	if err != nil {
+		return err
+	}
+	c.PackageVersion = version
+	if c.DistTag == "" {
+		c.DistTag = DistTagForVersion(c.PackageVersion)
+	}
+	return ValidateDistTag(c.DistTag)
+}
+
+// validateBinaries ensures that the configured binary is the first entry of the binaries list. Multiple binaries
+// require a match pattern to assign the input files.
+func (c *Config) validateBinaries() error {
+	if len(c.Binaries) == 0 {
+		c.Binaries = []Binary{{Name: c.BinName}}
+		return nil
+	}
+	binaries := make([]Binary, 0, len(c.Binaries))
+	names := make(map[string]bool)
+	for _, binary := range c.Binaries {
+		if !binNameRegexp.MatchString(binary.Name) {
+			return fmt.Errorf("invalid binary name: %q", binary.Name)
+		}
+		if names[binary.Name] {
+			return fmt.Errorf("duplicate binary: %s", binary.Name)
+		}
+		names[binary.Name] = true
+		if len(c.Binaries) > 1 && binary.Match == "" {
+			return fmt.Errorf("match pattern for binary %s is missing", binary.Name)
+		}
+		if _, err := path.Match(binary.Match, ""); err != nil {
+			return fmt.Errorf("invalid match pattern for binary %s: %w", binary.Name, err)
+		}
+		if binary.Name == c.BinName {
+			binaries = append([]Binary{binary}, binaries...)
+			continue
+		}
+		binaries = append(binaries, binary)
+	}
+	if !names[c.BinName] {
+		return fmt.Errorf("binary %s is missing in the list of binaries", c.BinName)
+	}
+	c.Binaries = binaries
+	return nil
+}
+
+// validatePlatforms normalizes the explicit platform mapping and ensures that every target is unique.
+func (c *Config) validatePlatforms() error {
+	if len(c.Platforms) == 0 {
+		return nil
+	}
+	if c.GoReleaserArtifacts {
+		return fmt.Errorf("platforms can not be combined with goreleaserArtifacts")
+	}
+	targets := make(map[string]bool)
+	for i := range c.Platforms {
+		p := &c.Platforms[i]
+		if p.OS == "" || p.CPU == "" || p.Path == "" {
+			return fmt.Errorf("platform %d: os, cpu and path are required", i+1)
+		}
+		p.OS, p.CPU = helper.NodePlatformAndArch(p.OS, p.CPU)
+		switch {
+		case p.Libc != "" && p.OS != "linux":
+			return fmt.Errorf("platform %s: libc is only supported for linux", p.Target())
+		case p.Libc != "" && p.Libc != helper.LibcGlibc && p.Libc != helper.LibcMusl:
+			return fmt.Errorf("platform %s: invalid libc %s (must be %s or %s)", p.Target(), p.Libc, helper.LibcGlibc, helper.LibcMusl)
+		}
+		if targets[p.Target()] {
+			return fmt.Errorf("duplicate platform: %s", p.Target())
+		}
+		targets[p.Target()] = true
+	}
+	return nil
+}
+
+// normalizeTarget converts Go names in the os and arch part of a target or target pattern (e.g. windows-amd64)
+// to the Node.js names (e.g. win32-x64).
+func normalizeTarget(target string) string {
+	parts := strings.SplitN(target, "-", 3)
+	if len(parts) < 2 {
+		return target
+	}
+	parts[0], parts[1] = helper.NodePlatformAndArch(parts[0], parts[1])
+	return strings.Join(parts, "-")
+}
+
+// validatePlatformFilters checks the include, exclude and prefer sources patterns and normalizes the platform
+// patterns and the required platforms.
+func (c *Config) validatePlatformFilters() error {
+	for _, patterns := range [][]string{c.IncludePlatforms, c.ExcludePlatforms} {
+		for i, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid platform pattern %q: %w", pattern, err)
+			}
+			patterns[i] = normalizeTarget(pattern)
+		}
+	}
+	for _, pattern := range c.PreferSources {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid prefer sources pattern %q: %w", pattern, err)
+		}
+	}
+	for i, required := range c.RequiredPlatforms {
+		platform, arch, found := strings.Cut(required, "-")
+		if !found || platform == "" || arch == "" {
+			return fmt.Errorf("invalid required platform %q (e.g. linux-x64)", required)
+		}
+		c.RequiredPlatforms[i] = normalizeTarget(required)
+	}
+	return nil
+}
+
+// validatePublishOptions checks the publish options. The concurrency defaults to DefaultPublishConcurrency, all
+// other options are used as given (the CLI sets their defaults), hence zero retries disable retries.
+func (c *Config) validatePublishOptions() error {
+	if c.PublishConcurrency == 0 {
+		c.PublishConcurrency = DefaultPublishConcurrency
+	}
+	if c.PublishConcurrency < 1 {
+		return fmt.Errorf("invalid publish concurrency: %d (must be at least 1)", c.PublishConcurrency)
+	}
+	if c.PublishRetries < 0 {
+		return fmt.Errorf("invalid publish retries: %d (must not be negative)", c.PublishRetries)
+	}
+	if c.PublishRetryDelay < 0 || c.PublishRetryMaxDelay < 0 {
+		return fmt.Errorf("invalid publish retry delay: %s (max: %s)", c.PublishRetryDelay, c.PublishRetryMaxDelay)
+	}
+	return nil
+}
+
+// HasMultipleBinaries returns true if the packages contain more than one binary.
+func (c *Config) HasMultipleBinaries() bool {
+	return len(c.Binaries) > 1
+}
+
+// MainPackageName returns the full name of the main package.
+func (c *Config) MainPackageName() string {
+	if c.NoPrefixForMainPackage {
+		return c.PackageName
+	}
+	return c.PackageNamePrefix + c.PackageName
+}
+
+// NormalizeVersion strips a leading v from the version and checks that it is a valid semantic version.
+func NormalizeVersion(version string) (string, error) {
+	normalized := strings.TrimPrefix(strings.TrimSpace(version), "v")
+	if !semverRegexp.MatchString(normalized) {
+		return "", fmt.Errorf("package version %q is not a valid semantic version (e.g. 1.2.3)", version)
+	}
+	return normalized, nil
+}
+
+// BinaryPathEnvForName returns the name of the environment variable that overrides the binary used by the
+// launcher (e.g. my-cool-cli -> MY_COOL_CLI_BINARY_PATH).
+func BinaryPathEnvForName(binName string) string {
+	name := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' {
+			return r - 'a' + 'A'
+		}
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, binName)
+	if name != "" && name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
+	return name + "_BINARY_PATH"
+}
+
+// DistTagForVersion returns latest for stable versions. For prerelease versions the first prerelease
+// identifier is used if it is a valid tag name (e.g. 1.2.0-beta.1 => beta), otherwise next.
+func DistTagForVersion(version string) string {
+	version, _, _ = strings.Cut(version, "+")
+	_, prerelease, found := strings.Cut(version, "-")
+	if !found {
+		return DefaultDistTag
+	}
+	identifier, _, _ := strings.Cut(prerelease, ".")
+	identifier = strings.ToLower(identifier)
+	if identifier != DefaultDistTag && ValidateDistTag(identifier) == nil {
+		return identifier
+	}
+	return DefaultPrereleaseDistTag
+}
+
+// ValidateDistTag checks that the tag can be used as npm dist-tag. npm rejects tags that are valid semver ranges,
+// as they would be ambiguous in package specs (e.g. my-cli@v1).
+func ValidateDistTag(tag string) error {
+	if !distTagRegexp.MatchString(tag) || semverRangeTagRegexp.MatchString(tag) {
+		return fmt.Errorf("invalid dist-tag: %s (must start with a letter, contain only letters, digits and dashes and must not be a semver range)", tag)
+	}
 	return nil
 }
 
@@ -88,3 +417,20 @@ func GetRepositoryAndHomepageFromEnv() EnvInfo {
 		Name:       packageName,
 	}
 }
+
+// GetVersionFromEnv returns the name of the tag that triggered the GitHub Actions workflow.
+func GetVersionFromEnv() string {
+	if os.Getenv("GITHUB_REF_TYPE") != "tag" {
+		return ""
+	}
+	return os.Getenv("GITHUB_REF_NAME")
+}
+
+// GetVersionFromGitTag returns the tag that points to the current git commit.
+func GetVersionFromGitTag() string {
+	out, err := exec.Command("git", "describe", "--tags", "--exact-match", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
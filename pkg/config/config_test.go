@@ -0,0 +1,219 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestDistTagForVersion(t *testing.T) {
+	tests := map[string]string{
+		"1.2.0":             "latest",
+		"1.2.0+build.5":     "latest",
+		"1.2.0-beta.1":      "beta",
+		"1.2.0-RC.2":        "rc",
+		"1.2.0-alpha":       "alpha",
+		"1.2.0-0.3.7":       "next",
+		"1.2.0-latest.1":    "next",
+		"1.2.0-Latest.1":    "next",
+		"1.2.0-V2":          "next",
+		"1.2.0-x.7.z.92+b1": "next",
+		"1.2.0-v2":          "next",
+	}
+	for version, want := range tests {
+		if got := DistTagForVersion(version); got != want {
+			t.Errorf("DistTagForVersion(%q) = %q, want %q", version, got, want)
+		}
+	}
+}
+
+func TestValidateDistTag(t *testing.T) {
+	for _, tag := range []string{"latest", "beta", "next-2", "x1", "V1"} {
+		if err := ValidateDistTag(tag); err != nil {
+			t.Errorf("ValidateDistTag(%q) = %v", tag, err)
+		}
+	}
+	for _, tag := range []string{"", "1", "1.2.0", "v1", "x", "X", "vx", "^1.2.0", "beta/1", "-beta"} {
+		if err := ValidateDistTag(tag); err == nil {
+			t.Errorf("ValidateDistTag(%q) must fail", tag)
+		}
+	}
+
+	c := &Config{BinName: "my-cli", InputBinDirPath: t.TempDir(), PackageVersion: "1.0.0", DistTag: "v1"}
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected invalid dist-tag error")
+	}
+}
+
+func TestBinaryPathEnvForName(t *testing.T) {
+	tests := map[string]string{
+		"my-cool-cli": "MY_COOL_CLI_BINARY_PATH",
+		"tool.v2":     "TOOL_V2_BINARY_PATH",
+		"7zip":        "_7ZIP_BINARY_PATH",
+	}
+	for name, want := range tests {
+		if got := BinaryPathEnvForName(name); got != want {
+			t.Errorf("BinaryPathEnvForName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestNormalizeVersion(t *testing.T) {
+	valid := map[string]string{
+		"1.2.3":              "1.2.3",
+		"v1.2.3":             "1.2.3",
+		"1.0.0-beta.1+build": "1.0.0-beta.1+build",
+	}
+	for version, want := range valid {
+		got, err := NormalizeVersion(version)
+		if err != nil || got != want {
+			t.Errorf("NormalizeVersion(%q) = %q, %v, want %q", version, got, err, want)
+		}
+	}
+	for _, version := range []string{"v1.2", "1.2.3.4", "01.2.3", "1.2.3-", "latest", ""} {
+		if _, err := NormalizeVersion(version); err == nil {
+			t.Errorf("NormalizeVersion(%q) should fail", version)
+		}
+	}
+}
+
+func TestValidateDerivesVersion(t *testing.T) {
+	inputDir := t.TempDir()
+	newConfig := func() *Config {
+		return &Config{BinName: "my-cli", InputBinDirPath: inputDir}
+	}
+
+	t.Setenv("GITHUB_REF_TYPE", "tag")
+	t.Setenv("GITHUB_REF_NAME", "v2.0.0-rc.1")
+	c := newConfig()
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if c.PackageVersion != "2.0.0-rc.1" || c.DistTag != "rc" {
+		t.Fatalf("version = %q, dist-tag = %q", c.PackageVersion, c.DistTag)
+	}
+
+	metadata := `{"project_name":"my-cli","tag":"v1.4.0","version":"1.4.0"}`
+	if err := os.WriteFile(filepath.Join(inputDir, "metadata.json"), []byte(metadata), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c = newConfig()
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if c.PackageVersion != "1.4.0" {
+		t.Fatalf("version = %q, want %q", c.PackageVersion, "1.4.0")
+	}
+
+	c = newConfig()
+	c.PackageVersion = "v1.2"
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected invalid version error")
+	}
+}
+
+func TestValidateBinaries(t *testing.T) {
+	newConfig := func(binName string, binaries ...Binary) *Config {
+		return &Config{BinName: binName, Binaries: binaries, InputBinDirPath: t.TempDir(), PackageVersion: "1.0.0"}
+	}
+
+	c := newConfig("my-cli", Binary{Name: "my-daemon", Match: "my-daemon_*"}, Binary{Name: "my-cli", Match: "my-cli_*"})
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if c.Binaries[0].Name != "my-cli" || c.Binaries[1].Name != "my-daemon" {
+		t.Fatalf("the main binary must be the first binary: %v", c.Binaries)
+	}
+
+	c = newConfig("", Binary{Name: "my-cli", Match: "my-cli_*"}, Binary{Name: "my-daemon", Match: "my-daemon_*"})
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if c.BinName != "my-cli" || c.PackageName != "my-cli" {
+		t.Fatalf("name = %q, package name = %q", c.BinName, c.PackageName)
+	}
+
+	invalid := map[string]*Config{
+		"missing main binary": newConfig("my-cli", Binary{Name: "my-daemon", Match: "my-daemon_*"}),
+		"missing pattern":     newConfig("my-cli", Binary{Name: "my-cli"}, Binary{Name: "my-daemon", Match: "my-daemon_*"}),
+		"invalid pattern":     newConfig("my-cli", Binary{Name: "my-cli", Match: "["}),
+		"invalid name":        newConfig("my-cli", Binary{Name: "my-cli", Match: "my-cli_*"}, Binary{Name: "../x", Match: "x_*"}),
+		"duplicate binary":    newConfig("my-cli", Binary{Name: "my-cli", Match: "a_*"}, Binary{Name: "my-cli", Match: "b_*"}),
+	}
+	for name, c := range invalid {
+		if err := c.Validate(); err == nil {
+			t.Errorf("%s: expected validation error", name)
+		}
+	}
+}
+
+func TestValidatePlatforms(t *testing.T) {
+	c := &Config{BinName: "my-cli", PackageVersion: "1.0.0", Platforms: []Platform{
+		{OS: "windows", CPU: "amd64", Path: "out-win"},
+		{OS: "linux", CPU: "arm64", Libc: "musl", Path: "out-lnx"},
+	}}
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Platforms[0].Target(); got != "win32-x64" {
+		t.Fatalf("target = %q, want %q", got, "win32-x64")
+	}
+
+	invalid := map[string][]Platform{
+		"missing path":    {{OS: "linux", CPU: "x64"}},
+		"libc on darwin":  {{OS: "darwin", CPU: "arm64", Libc: "musl", Path: "out"}},
+		"invalid libc":    {{OS: "linux", CPU: "x64", Libc: "uclibc", Path: "out"}},
+		"duplicate entry": {{OS: "linux", CPU: "x64", Path: "a"}, {OS: "linux", CPU: "amd64", Path: "b"}},
+	}
+	for name, platforms := range invalid {
+		c := &Config{BinName: "my-cli", PackageVersion: "1.0.0", Platforms: platforms}
+		if err := c.Validate(); err == nil {
+			t.Errorf("%s: expected validation error", name)
+		}
+	}
+}
+
+func TestValidatePlatformFilters(t *testing.T) {
+	c := &Config{BinName: "my-cli", InputBinDirPath: t.TempDir(), PackageVersion: "1.0.0",
+		RequiredPlatforms: []string{"linux-amd64-musl", "darwin-arm64"},
+		IncludePlatforms:  []string{"windows-386", "linux-*"},
+		ExcludePlatforms:  []string{"*-amd64-musl", "plan9"}}
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if c.RequiredPlatforms[0] != "linux-x64-musl" || c.RequiredPlatforms[1] != "darwin-arm64" {
+		t.Fatalf("unexpected required platforms: %v", c.RequiredPlatforms)
+	}
+	if !slices.Equal(c.IncludePlatforms, []string{"win32-ia32", "linux-*"}) || !slices.Equal(c.ExcludePlatforms, []string{"*-x64-musl", "plan9"}) {
+		t.Fatalf("unexpected platform patterns: %v %v", c.IncludePlatforms, c.ExcludePlatforms)
+	}
+	for _, c := range []*Config{
+		{BinName: "my-cli", InputBinDirPath: t.TempDir(), PackageVersion: "1.0.0", ExcludePlatforms: []string{"["}},
+		{BinName: "my-cli", InputBinDirPath: t.TempDir(), PackageVersion: "1.0.0", RequiredPlatforms: []string{"linux"}},
+	} {
+		if err := c.Validate(); err == nil {
+			t.Errorf("expected validation error for %+v", c)
+		}
+	}
+}
+
+func TestValidatePublishOptions(t *testing.T) {
+	c := &Config{BinName: "my-cli", InputBinDirPath: t.TempDir(), PackageVersion: "1.0.0"}
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if c.PublishConcurrency != DefaultPublishConcurrency || c.PublishRetries != 0 || c.PublishRetryDelay != 0 {
+		t.Fatalf("unexpected publish options: %+v", c)
+	}
+	for _, c := range []*Config{
+		{BinName: "my-cli", InputBinDirPath: t.TempDir(), PackageVersion: "1.0.0", PublishConcurrency: -1},
+		{BinName: "my-cli", InputBinDirPath: t.TempDir(), PackageVersion: "1.0.0", PublishRetries: -1},
+		{BinName: "my-cli", InputBinDirPath: t.TempDir(), PackageVersion: "1.0.0", PublishRetryDelay: -time.Second},
+	} {
+		if err := c.Validate(); err == nil {
+			t.Errorf("expected validation error for %+v", c)
+		}
+	}
+}
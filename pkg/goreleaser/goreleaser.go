@@ -0,0 +1,98 @@
+package goreleaser
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	ArtifactsFileName = "artifacts.json"
+	MetadataFileName  = "metadata.json"
+)
+
+// ArtifactTypeBinary is the type of the built binaries (before they are archived).
+const ArtifactTypeBinary = "Binary"
+
+// ArtifactTypeUniversalBinary is the type of macOS universal binaries, which contain the amd64 and arm64 binary.
+const ArtifactTypeUniversalBinary = "Universal Binary"
+
+// Artifact is an entry of GoReleaser's dist/artifacts.json.
+type Artifact struct {
+	Name    string         `json:"name"`
+	Path    string         `json:"path"`
+	Goos    string         `json:"goos"`
+	Goarch  string         `json:"goarch"`
+	Goarm   string         `json:"goarm"`
+	Goamd64 string         `json:"goamd64"`
+	Type    string         `json:"type"`
+	Extra   map[string]any `json:"extra"`
+}
+
+// BinaryName returns the name of the binary as configured in the GoReleaser build.
+func (a *Artifact) BinaryName() string {
+	if binary, ok := a.Extra["Binary"].(string); ok {
+		return binary
+	}
+	return ""
+}
+
+// Checksum returns the hex encoded checksum GoReleaser calculated for the artifact (extra.Checksum is in the
+// format <algorithm>:<hex>) or an empty string if it was not checksummed.
+func (a *Artifact) Checksum() string {
+	checksum, ok := a.Extra["Checksum"].(string)
+	if !ok {
+		return ""
+	}
+	if _, hexChecksum, found := strings.Cut(checksum, ":"); found {
+		return hexChecksum
+	}
+	return checksum
+}
+
+// Metadata is the content of GoReleaser's dist/metadata.json.
+type Metadata struct {
+	ProjectName string `json:"project_name"`
+	Tag         string `json:"tag"`
+	Version     string `json:"version"`
+	Commit      string `json:"commit"`
+}
+
+func readJSONFile(filePath string, v any) error {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// ReadArtifacts reads the artifacts.json of the given dist directory.
+func ReadArtifacts(distDir string) ([]*Artifact, error) {
+	artifacts := make([]*Artifact, 0)
+	if err := readJSONFile(filepath.Join(distDir, ArtifactsFileName), &artifacts); err != nil {
+		return nil, err
+	}
+	return artifacts, nil
+}
+
+// ReadMetadata reads the metadata.json of the given dist directory.
+func ReadMetadata(distDir string) (*Metadata, error) {
+	metadata := &Metadata{}
+	if err := readJSONFile(filepath.Join(distDir, MetadataFileName), metadata); err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
+
+// ResolveArtifactPath returns the path of an artifact. Relative artifact paths are relative to the
+// directory GoReleaser was run in, which is usually the parent directory of the dist directory.
+func ResolveArtifactPath(distDir string, artifact *Artifact) string {
+	if filepath.IsAbs(artifact.Path) {
+		return artifact.Path
+	}
+	if _, err := os.Stat(artifact.Path); err == nil {
+		return artifact.Path
+	}
+	return filepath.Join(filepath.Dir(filepath.Clean(distDir)), artifact.Path)
+}
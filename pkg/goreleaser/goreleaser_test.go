@@ -0,0 +1,56 @@
+package goreleaser
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testArtifacts = `[
+  {"name":"my-cli","path":"dist/my-cli_linux_arm_7/my-cli","goos":"linux","goarch":"arm","goarm":"7","internal_type":4,"type":"Binary","extra":{"Binary":"my-cli","Checksum":"sha256:9a3a45d01531a20e89ac6ae10b0b0beb0492acd7216a368aa062d1a5fecaf9cd","ID":"my-cli"}},
+  {"name":"my-cli_checksums.txt","path":"dist/my-cli_checksums.txt","internal_type":12,"type":"Checksum"}
+]`
+
+func TestReadArtifactsAndMetadata(t *testing.T) {
+	projectDir := t.TempDir()
+	distDir := filepath.Join(projectDir, "dist")
+	if err := os.Mkdir(distDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(distDir, ArtifactsFileName), []byte(testArtifacts), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(distDir, MetadataFileName), []byte(`{"project_name":"my-cli","tag":"v1.2.3","version":"1.2.3"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	artifacts, err := ReadArtifacts(distDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(artifacts) != 2 {
+		t.Fatalf("got %d artifacts, want 2", len(artifacts))
+	}
+	binary := artifacts[0]
+	if binary.Type != ArtifactTypeBinary || binary.BinaryName() != "my-cli" || binary.Goarm != "7" {
+		t.Fatalf("unexpected artifact %+v", binary)
+	}
+	if got := binary.Checksum(); got != "9a3a45d01531a20e89ac6ae10b0b0beb0492acd7216a368aa062d1a5fecaf9cd" {
+		t.Fatalf("checksum = %q", got)
+	}
+	if got := artifacts[1].Checksum(); got != "" {
+		t.Fatalf("checksum = %q, want none", got)
+	}
+	wantPath := filepath.Join(projectDir, "dist", "my-cli_linux_arm_7", "my-cli")
+	if got := ResolveArtifactPath(distDir, binary); got != wantPath {
+		t.Fatalf("ResolveArtifactPath = %q, want %q", got, wantPath)
+	}
+
+	metadata, err := ReadMetadata(distDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metadata.Version != "1.2.3" || metadata.Tag != "v1.2.3" {
+		t.Fatalf("unexpected metadata %+v", metadata)
+	}
+}
@@ -0,0 +1,229 @@
+package helper
+
+import (
+	"debug/elf"
+	"debug/macho"
+	"debug/pe"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+var ErrUnknownBinaryFormat = errors.New("unknown binary format")
+
+// BinaryInfo describes the platform and architectures found in the header of an executable.
+type BinaryInfo struct {
+	Format string
+	// Platform is the Node.js platform of the binary. It is empty if the header does not
+	// specify the operating system (e.g. most Linux ELF binaries use the System V ABI).
+	Platform string
+	// Archs contains the Node.js architectures of the binary. Fat Mach-O binaries contain more than one.
+	Archs []string
+	// Libc is the C library a dynamically linked Linux binary was built against (glibc or musl).
+	Libc string
+}
+
+// elfPlatforms are all platforms that use ELF binaries without necessarily setting the OS/ABI field.
+var elfPlatforms = []string{"linux", "android", "freebsd", "netbsd", "openbsd", "dragonfly", "sunos"}
+
+// Matches reports whether the given Node.js platform and arch are compatible with the binary header.
+func (b *BinaryInfo) Matches(platform, arch string) bool {
+	if !slices.Contains(b.Archs, arch) {
+		return false
+	}
+	if b.Platform != "" {
+		return b.Platform == platform
+	}
+	return b.Format == "elf" && slices.Contains(elfPlatforms, platform)
+}
+
+// DefaultPlatform returns the platform of the binary, assuming Linux for ELF binaries that do not specify it.
+func (b *BinaryInfo) DefaultPlatform() string {
+	if b.Platform == "" && b.Format == "elf" {
+		return "linux"
+	}
+	return b.Platform
+}
+
+// ReadBinaryInfo reads the ELF, Mach-O (including fat/universal) or PE header of the given file.
+func ReadBinaryInfo(filePath string) (*BinaryInfo, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	magic := make([]byte, 4)
+	if _, err := io.ReadFull(f, magic); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+			return nil, ErrUnknownBinaryFormat
+		}
+		return nil, err
+	}
+
+	switch {
+	case string(magic) == elf.ELFMAG:
+		return readElfInfo(f)
+	case string(magic[:2]) == "MZ":
+		return readPeInfo(f)
+	}
+	if fatFile, err := macho.NewFatFile(f); err == nil {
+		archs := make([]string, 0, len(fatFile.Arches))
+		for _, fatArch := range fatFile.Arches {
+			if arch := machoArch(fatArch.Cpu); arch != "" && !slices.Contains(archs, arch) {
+				archs = append(archs, arch)
+			}
+		}
+		return newBinaryInfo("macho", "darwin", archs...)
+	}
+	if machoFile, err := macho.NewFile(f); err == nil {
+		return newBinaryInfo("macho", "darwin", machoArch(machoFile.Cpu))
+	}
+	return nil, ErrUnknownBinaryFormat
+}
+
+func newBinaryInfo(format, goos string, goarchs ...string) (*BinaryInfo, error) {
+	archs := make([]string, 0, len(goarchs))
+	for _, goarch := range goarchs {
+		if goarch == "" {
+			continue
+		}
+		archs = append(archs, toNodeArch(goarch))
+	}
+	if len(archs) == 0 {
+		return nil, fmt.Errorf("unsupported %s architecture", format)
+	}
+	platform := ""
+	if goos != "" {
+		platform = toNodePlatform(goos)
+	}
+	return &BinaryInfo{Format: format, Platform: platform, Archs: archs}, nil
+}
+
+func readElfInfo(f *os.File) (*BinaryInfo, error) {
+	elfFile, err := elf.NewFile(f)
+	if err != nil {
+		return nil, err
+	}
+	goos := ""
+	switch elfFile.OSABI {
+	case elf.ELFOSABI_LINUX:
+		goos = "linux"
+	case elf.ELFOSABI_FREEBSD:
+		goos = "freebsd"
+	case elf.ELFOSABI_NETBSD:
+		goos = "netbsd"
+	case elf.ELFOSABI_OPENBSD:
+		goos = "openbsd"
+	case elf.ELFOSABI_SOLARIS:
+		goos = "solaris"
+	}
+	info, err := newBinaryInfo("elf", goos, elfArch(elfFile))
+	if err != nil {
+		return nil, err
+	}
+	info.Libc = elfLibc(elfFile)
+	return info, nil
+}
+
+// elfLibc determines the libc from the dynamic linker (program interpreter) of the binary.
+func elfLibc(f *elf.File) string {
+	for _, prog := range f.Progs {
+		if prog.Type != elf.PT_INTERP {
+			continue
+		}
+		interp, err := io.ReadAll(prog.Open())
+		if err != nil {
+			return ""
+		}
+		switch {
+		case strings.Contains(string(interp), "ld-musl"):
+			return LibcMusl
+		case strings.Contains(string(interp), "ld-linux"):
+			return LibcGlibc
+		}
+	}
+	return ""
+}
+
+func elfArch(f *elf.File) string {
+	is64 := f.Class == elf.ELFCLASS64
+	isLE := f.Data == elf.ELFDATA2LSB
+	switch f.Machine {
+	case elf.EM_X86_64:
+		return "amd64"
+	case elf.EM_386:
+		return "386"
+	case elf.EM_AARCH64:
+		return "arm64"
+	case elf.EM_ARM:
+		return "arm"
+	case elf.EM_PPC64:
+		if isLE {
+			return "ppc64le"
+		}
+		return "ppc64"
+	case elf.EM_S390:
+		return "s390x"
+	case elf.EM_RISCV:
+		if is64 {
+			return "riscv64"
+		}
+	case elf.EM_LOONGARCH:
+		if is64 {
+			return "loong64"
+		}
+	case elf.EM_MIPS, elf.EM_MIPS_RS3_LE:
+		arch := "mips"
+		if is64 {
+			arch = "mips64"
+		}
+		if isLE {
+			arch += "le"
+		}
+		return arch
+	}
+	return ""
+}
+
+func machoArch(cpu macho.Cpu) string {
+	switch cpu {
+	case macho.CpuAmd64:
+		return "amd64"
+	case macho.Cpu386:
+		return "386"
+	case macho.CpuArm64:
+		return "arm64"
+	case macho.CpuArm:
+		return "arm"
+	case macho.CpuPpc64:
+		return "ppc64"
+	}
+	return ""
+}
+
+func readPeInfo(f *os.File) (*BinaryInfo, error) {
+	peFile, err := pe.NewFile(f)
+	if err != nil {
+		return nil, err
+	}
+	arch := ""
+	switch peFile.Machine {
+	case pe.IMAGE_FILE_MACHINE_AMD64:
+		arch = "amd64"
+	case pe.IMAGE_FILE_MACHINE_I386:
+		arch = "386"
+	case pe.IMAGE_FILE_MACHINE_ARM64:
+		arch = "arm64"
+	case pe.IMAGE_FILE_MACHINE_ARMNT, pe.IMAGE_FILE_MACHINE_ARM:
+		arch = "arm"
+	case pe.IMAGE_FILE_MACHINE_RISCV64:
+		arch = "riscv64"
+	case pe.IMAGE_FILE_MACHINE_LOONGARCH64:
+		arch = "loong64"
+	}
+	return newBinaryInfo("pe", "windows", arch)
+}
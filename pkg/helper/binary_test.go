@@ -0,0 +1,144 @@
+package helper
+
+import (
+	"bytes"
+	"debug/elf"
+	"debug/macho"
+	"debug/pe"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func writeTestFile(t *testing.T, data []byte) string {
+	t.Helper()
+	filePath := filepath.Join(t.TempDir(), "bin")
+	if err := os.WriteFile(filePath, data, 0755); err != nil {
+		t.Fatal(err)
+	}
+	return filePath
+}
+
+func elfHeader(osABI elf.OSABI, machine elf.Machine) []byte {
+	buf := &bytes.Buffer{}
+	ident := [elf.EI_NIDENT]byte{0x7f, 'E', 'L', 'F', byte(elf.ELFCLASS64), byte(elf.ELFDATA2LSB), byte(elf.EV_CURRENT), byte(osABI)}
+	_ = binary.Write(buf, binary.LittleEndian, elf.Header64{
+		Ident:     ident,
+		Type:      uint16(elf.ET_EXEC),
+		Machine:   uint16(machine),
+		Version:   uint32(elf.EV_CURRENT),
+		Ehsize:    64,
+		Phentsize: 56,
+		Shentsize: 64,
+	})
+	return buf.Bytes()
+}
+
+func machoHeader(cpu macho.Cpu) []byte {
+	buf := &bytes.Buffer{}
+	_ = binary.Write(buf, binary.LittleEndian, macho.FileHeader{
+		Magic: macho.Magic64,
+		Cpu:   cpu,
+		Type:  macho.TypeExec,
+	})
+	// reserved field of the 64-bit header
+	_ = binary.Write(buf, binary.LittleEndian, uint32(0))
+	return buf.Bytes()
+}
+
+func peFile(machine uint16) []byte {
+	const peOffset = 0x40
+	out := make([]byte, peOffset)
+	copy(out, "MZ")
+	binary.LittleEndian.PutUint32(out[0x3c:], peOffset)
+	buf := bytes.NewBuffer(out)
+	buf.WriteString("PE\x00\x00")
+	_ = binary.Write(buf, binary.LittleEndian, pe.FileHeader{Machine: machine, Characteristics: pe.IMAGE_FILE_EXECUTABLE_IMAGE})
+	// padding for the (empty) symbol and string tables
+	buf.Write(make([]byte, 64))
+	return buf.Bytes()
+}
+
+func fatMachoFile(cpus ...macho.Cpu) []byte {
+	const align = 0x1000
+	header := &bytes.Buffer{}
+	_ = binary.Write(header, binary.BigEndian, []uint32{macho.MagicFat, uint32(len(cpus))})
+	out := make([]byte, align*(len(cpus)+1))
+	for i, cpu := range cpus {
+		data := machoHeader(cpu)
+		offset := align * (i + 1)
+		_ = binary.Write(header, binary.BigEndian, macho.FatArchHeader{Cpu: cpu, Offset: uint32(offset), Size: uint32(len(data)), Align: 12})
+		copy(out[offset:], data)
+	}
+	copy(out, header.Bytes())
+	return out
+}
+
+func TestReadBinaryInfoOfTestExecutable(t *testing.T) {
+	info, err := ReadBinaryInfo(os.Args[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	platform, arch := toNodePlatform(runtime.GOOS), toNodeArch(runtime.GOARCH)
+	if !info.Matches(platform, arch) {
+		t.Fatalf("binary info %+v does not match %s-%s", info, platform, arch)
+	}
+}
+
+func TestReadBinaryInfo(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		platform string
+		archs    []string
+	}{
+		{"elf-none", elfHeader(elf.ELFOSABI_NONE, elf.EM_X86_64), "linux", []string{"x64"}},
+		{"elf-freebsd", elfHeader(elf.ELFOSABI_FREEBSD, elf.EM_AARCH64), "freebsd", []string{"arm64"}},
+		{"macho", machoHeader(macho.CpuArm64), "darwin", []string{"arm64"}},
+		{"macho-fat", fatMachoFile(macho.CpuAmd64, macho.CpuArm64), "darwin", []string{"x64", "arm64"}},
+		{"pe-amd64", peFile(pe.IMAGE_FILE_MACHINE_AMD64), "win32", []string{"x64"}},
+		{"pe-arm64", peFile(pe.IMAGE_FILE_MACHINE_ARM64), "win32", []string{"arm64"}},
+		{"pe-386", peFile(pe.IMAGE_FILE_MACHINE_I386), "win32", []string{"ia32"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := ReadBinaryInfo(writeTestFile(t, tt.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.DefaultPlatform() != tt.platform {
+				t.Fatalf("platform = %q, want %q", info.DefaultPlatform(), tt.platform)
+			}
+			if len(info.Archs) != len(tt.archs) {
+				t.Fatalf("archs = %v, want %v", info.Archs, tt.archs)
+			}
+			for i := range tt.archs {
+				if info.Archs[i] != tt.archs[i] {
+					t.Fatalf("archs = %v, want %v", info.Archs, tt.archs)
+				}
+			}
+		})
+	}
+}
+
+func TestReadBinaryInfoUnknownFormat(t *testing.T) {
+	if _, err := ReadBinaryInfo(writeTestFile(t, []byte("#!/bin/sh\necho hi\n"))); err != ErrUnknownBinaryFormat {
+		t.Fatalf("err = %v, want %v", err, ErrUnknownBinaryFormat)
+	}
+}
+
+func TestBinaryInfoMatches(t *testing.T) {
+	elfInfo := &BinaryInfo{Format: "elf", Archs: []string{"x64"}}
+	if !elfInfo.Matches("linux", "x64") || !elfInfo.Matches("android", "x64") {
+		t.Fatal("ELF binary without OS/ABI should match ELF platforms")
+	}
+	if elfInfo.Matches("win32", "x64") || elfInfo.Matches("linux", "arm64") {
+		t.Fatal("ELF binary should not match other formats or archs")
+	}
+	peInfo := &BinaryInfo{Format: "pe", Platform: "win32", Archs: []string{"x64"}}
+	if peInfo.Matches("linux", "x64") {
+		t.Fatal("PE binary should only match win32")
+	}
+}
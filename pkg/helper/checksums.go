@@ -0,0 +1,77 @@
+package helper
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ReadChecksumsFile parses a checksums file in the format of sha256sum (and GoReleaser) and
+// returns a map from the slash separated file path as listed in the file to hex encoded checksum.
+func ReadChecksumsFile(filePath string) (map[string]string, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	checksums := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		checksum, fileName, found := strings.Cut(line, " ")
+		fileName = strings.TrimPrefix(strings.TrimSpace(fileName), "*")
+		if !found || fileName == "" {
+			return nil, fmt.Errorf("%s:%d: invalid checksum line", filePath, lineNumber)
+		}
+		checksums[path.Clean(filepath.ToSlash(fileName))] = strings.ToLower(checksum)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return checksums, nil
+}
+
+func newChecksumHash(checksum string) (hash.Hash, error) {
+	switch len(checksum) {
+	case sha1.Size * 2:
+		return sha1.New(), nil
+	case sha256.Size * 2:
+		return sha256.New(), nil
+	case sha512.Size * 2:
+		return sha512.New(), nil
+	}
+	return nil, fmt.Errorf("unsupported checksum %s", checksum)
+}
+
+// VerifyChecksum compares the checksum of the file with the expected hex encoded sha1, sha256 or sha512 checksum.
+func VerifyChecksum(filePath, checksum string) error {
+	h, err := newChecksumHash(checksum)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := io.Copy(h, f); err != nil {
+		return err
+	}
+	if actual := hex.EncodeToString(h.Sum(nil)); actual != checksum {
+		return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", filePath, checksum, actual)
+	}
+	return nil
+}
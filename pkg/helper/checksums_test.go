@@ -0,0 +1,35 @@
+package helper
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestVerifyChecksumsFile(t *testing.T) {
+	dir := t.TempDir()
+	binPath := filepath.Join(dir, "my-cli_linux_amd64")
+	if err := os.WriteFile(binPath, []byte("binary"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	checksumsPath := filepath.Join(dir, "my-cli_checksums.txt")
+	checksumsData := "9a3a45d01531a20e89ac6ae10b0b0beb0492acd7216a368aa062d1a5fecaf9cd  my-cli_linux_amd64\n" +
+		"0000000000000000000000000000000000000000000000000000000000000000 *dist/my-cli_darwin_arm64\n"
+	if err := os.WriteFile(checksumsPath, []byte(checksumsData), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	checksums, err := ReadChecksumsFile(checksumsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(checksums) != 2 || checksums["dist/my-cli_darwin_arm64"] == "" {
+		t.Fatalf("unexpected checksums %v", checksums)
+	}
+	if err := VerifyChecksum(binPath, checksums["my-cli_linux_amd64"]); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyChecksum(binPath, checksums["dist/my-cli_darwin_arm64"]); err == nil {
+		t.Fatal("expected checksum mismatch")
+	}
+}
@@ -5,15 +5,40 @@ import (
 	"io"
 	"os"
 	"path"
+	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
 )
 
+const (
+	LibcGlibc = "glibc"
+	LibcMusl  = "musl"
+)
+
 type BinFile struct {
+	// BinName is the name of the configured binary this file belongs to.
+	BinName  string
 	Platform string
 	Arch     string
-	Path     string
+	// Libc is only set for Linux binaries if both glibc and musl variants are released.
+	Libc string
+	Path string
+	// Source is the input file the binary was taken from (e.g. the archive it was extracted from).
+	Source   string
 	FileName string
+	// Variant is the GOARM or GOAMD64 value of the binary if known (e.g. 7 or v3).
+	Variant string
+	// ExtraFiles are paths relative to the directory of Path that are included in the package.
+	ExtraFiles []string
+}
+
+// Target returns the platform identifier used in package names (e.g. linux-x64 or linux-x64-musl).
+func (b *BinFile) Target() string {
+	if b.Libc != "" {
+		return fmt.Sprintf("%s-%s-%s", b.Platform, b.Arch, b.Libc)
+	}
+	return fmt.Sprintf("%s-%s", b.Platform, b.Arch)
 }
 
 func CopyFile(from, to string) (err error) {
@@ -48,7 +73,7 @@ func CopyFile(from, to string) (err error) {
 
 func toNodePlatform(platform string) string {
 	switch platform {
-	case "solaris":
+	case "solaris", "illumos":
 		return "sunos"
 	case "windows":
 		return "win32"
@@ -58,17 +83,28 @@ func toNodePlatform(platform string) string {
 
 func toNodeArch(arch string) string {
 	switch arch {
-	case "386":
+	case "386", "i386":
 		return "ia32"
-	case "amd64":
+	case "amd64", "x86_64":
 		return "x64"
 	}
 	return arch
 }
 
-var osArchRegexp = regexp.MustCompile("(?i)(android|darwin|dragonfly|freebsd|linux|nacl|netbsd|openbsd|plan9|solaris|windows)(_|-)(i?386|amd64p32|amd64|arm64|arm|mips64le|mips64|mipsle|mips|ppc64le|ppc64|s390x|x86_64)")
+// NodePlatformAndArch converts GOOS and GOARCH to the corresponding Node.js platform and arch.
+func NodePlatformAndArch(goos, goarch string) (string, string) {
+	return toNodePlatform(goos), toNodeArch(goarch)
+}
+
+// osArchRegexp matches the os and arch of a file name and the optional GOARM or GOAMD64 variant that follows the
+// arch (e.g. arm_7, armv7 or amd64_v3). A variant without v requires a separator and must not be followed by a
+// version (e.g. my-cli_linux_arm_5.0.1).
+var osArchRegexp = regexp.MustCompile("(?i)(android|darwin|dragonfly|freebsd|linux|nacl|netbsd|openbsd|plan9|solaris|windows)(_|-)(i?386|amd64p32|amd64|arm64|arm|loong64|mips64le|mips64|mipsle|mips|ppc64le|ppc64|riscv64|s390x|x86_64)(?:(?:[_-]?(v[1-7])|[_-]([5-7]))(?:[^0-9a-z.]|\\.[a-z]|$))?")
 
 func ExtractOsAndArchFromFileName(fileName string) (string, string) {
+	if triple := ParseTargetTriple(fileName); triple != nil {
+		return toNodePlatform(triple.GoOS()), toNodeArch(triple.GoArch())
+	}
 	osArch := osArchRegexp.FindAllStringSubmatch(fileName, -1)
 	if len(osArch) < 1 || len(osArch[0]) < 4 {
 		return "", ""
@@ -76,6 +112,70 @@ func ExtractOsAndArchFromFileName(fileName string) (string, string) {
 	return toNodePlatform(strings.ToLower(osArch[0][1])), toNodeArch(strings.ToLower(osArch[0][3]))
 }
 
+// ExtractVariantFromFileName returns the GOARM (e.g. 7) or GOAMD64 (e.g. v3) value in the file name
+// (e.g. my-cli_linux_arm_7 or my-cli_linux_amd64_v3).
+func ExtractVariantFromFileName(fileName string) string {
+	if triple := ParseTargetTriple(fileName); triple != nil {
+		return NormalizeVariant(toNodeArch(triple.GoArch()), triple.GoArm())
+	}
+	osArch := osArchRegexp.FindStringSubmatch(fileName)
+	if osArch == nil {
+		return ""
+	}
+	return NormalizeVariant(toNodeArch(strings.ToLower(osArch[3])), osArch[4]+osArch[5])
+}
+
+// NormalizeVariant returns the GOARM (5-7) or GOAMD64 (v1-v4) value for the Node.js arch or an empty string
+// if the variant does not apply to the arch.
+func NormalizeVariant(arch, variant string) string {
+	variant = strings.ToLower(variant)
+	switch arch {
+	case "arm":
+		variant = strings.TrimPrefix(variant, "v")
+		if len(variant) == 1 && variant >= "5" && variant <= "7" {
+			return variant
+		}
+	case "x64":
+		if len(variant) == 2 && variant[0] == 'v' && variant[1] >= '1' && variant[1] <= '4' {
+			return variant
+		}
+	}
+	return ""
+}
+
+// DefaultVariant returns the variant that Go uses by default for the Node.js arch (GOARM=7, GOAMD64=v1).
+func DefaultVariant(arch string) string {
+	switch arch {
+	case "arm":
+		return "7"
+	case "x64":
+		return "v1"
+	}
+	return ""
+}
+
+var libcRegexp = regexp.MustCompile("(?i)(?:^|[^a-z])(musl|glibc|gnu)(?:[^a-z]|$)")
+
+// ExtractLibcFromFileName returns the libc (glibc or musl) indicated by a target triple ABI or a musl/gnu/glibc
+// token after the os and arch of the file name (e.g. my-cli_linux_amd64_musl, but not gnu-tool_linux_amd64).
+func ExtractLibcFromFileName(fileName string) string {
+	abi := ""
+	if triple := ParseTargetTriple(fileName); triple != nil {
+		abi = triple.ABI
+	} else if loc := osArchRegexp.FindStringIndex(fileName); loc != nil {
+		if match := libcRegexp.FindStringSubmatch(fileName[loc[1]:]); match != nil {
+			abi = strings.ToLower(match[1])
+		}
+	}
+	switch {
+	case strings.HasPrefix(abi, "musl"):
+		return LibcMusl
+	case strings.HasPrefix(abi, "gnu"), abi == "glibc":
+		return LibcGlibc
+	}
+	return ""
+}
+
 func EnsureOutputDirectory(path string) error {
 	_, err := os.Stat(path)
 	if err == nil {
@@ -105,3 +205,101 @@ func FindFirstExecutableFileInDir(dir string) (string, error) {
 	}
 	return "", fmt.Errorf("no executable file was found")
 }
+
+// FindExecutableFile searches dir recursively for an executable. If pattern is set, the first file whose
+// path relative to dir matches the glob pattern is returned. Otherwise, a file named binName (or binName.exe)
+// is preferred over the first file with a known executable header.
+func FindExecutableFile(dir, pattern, binName string) (string, error) {
+	files, err := listFiles(dir)
+	if err != nil {
+		return "", err
+	}
+	if pattern != "" {
+		for _, file := range files {
+			if ok, err := path.Match(pattern, file); err != nil {
+				return "", err
+			} else if ok {
+				return filepath.Join(dir, filepath.FromSlash(file)), nil
+			}
+		}
+		return "", fmt.Errorf("no file matching %s was found", pattern)
+	}
+	if filePath, ok := findFileByName(dir, files, binName); ok {
+		return filePath, nil
+	}
+	for _, file := range files {
+		filePath := filepath.Join(dir, filepath.FromSlash(file))
+		if _, err := ReadBinaryInfo(filePath); err == nil {
+			return filePath, nil
+		}
+	}
+	return "", fmt.Errorf("no executable file was found")
+}
+
+// FindExtraFiles returns the paths relative to dir of all files (except exclude) that match one of the glob patterns.
+func FindExtraFiles(dir string, patterns []string, exclude ...string) ([]string, error) {
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+	files, err := listFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	extraFiles := make([]string, 0)
+	for _, file := range files {
+		if slices.Contains(exclude, file) {
+			continue
+		}
+		for _, pattern := range patterns {
+			if ok, err := path.Match(pattern, file); err != nil {
+				return nil, err
+			} else if ok {
+				extraFiles = append(extraFiles, file)
+				break
+			}
+		}
+	}
+	return extraFiles, nil
+}
+
+// FindExecutableFileByName returns the path of the file named binName (or binName.exe) in dir or its subdirectories.
+func FindExecutableFileByName(dir, binName string) (string, error) {
+	files, err := listFiles(dir)
+	if err != nil {
+		return "", err
+	}
+	if filePath, ok := findFileByName(dir, files, binName); ok {
+		return filePath, nil
+	}
+	return "", fmt.Errorf("no file named %s was found", binName)
+}
+
+func findFileByName(dir string, files []string, binName string) (string, bool) {
+	for _, file := range files {
+		fileName := path.Base(file)
+		if fileName == binName || fileName == binName+".exe" {
+			return filepath.Join(dir, filepath.FromSlash(file)), true
+		}
+	}
+	return "", false
+}
+
+// listFiles returns the slash separated paths relative to dir of all regular files in dir.
+func listFiles(dir string) ([]string, error) {
+	files := make([]string, 0)
+	err := filepath.WalkDir(dir, func(filePath string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		relPath, err := filepath.Rel(dir, filePath)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(relPath))
+		return nil
+	})
+	return files, err
+}
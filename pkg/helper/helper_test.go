@@ -0,0 +1,104 @@
+package helper
+
+import "testing"
+
+func TestExtractOsAndArchFromFileName(t *testing.T) {
+	tests := map[string][2]string{
+		"my-cli_linux_amd64":                     {"linux", "x64"},
+		"my-cli_windows_386.exe":                 {"win32", "ia32"},
+		"my-cli-darwin-arm64":                    {"darwin", "arm64"},
+		"my-cli-linux-x86_64":                    {"linux", "x64"},
+		"mytool-x86_64-unknown-linux-musl":       {"linux", "x64"},
+		"mytool-aarch64-apple-darwin.tar.gz":     {"darwin", "arm64"},
+		"mytool-x86_64-pc-windows-msvc.exe":      {"win32", "x64"},
+		"mytool-i686-pc-windows-gnu.exe":         {"win32", "ia32"},
+		"mytool-armv7-unknown-linux-gnueabihf":   {"linux", "arm"},
+		"mytool-aarch64-linux-android":           {"android", "arm64"},
+		"mytool-riscv64gc-unknown-linux-gnu":     {"linux", "riscv64"},
+		"mytool-powerpc64le-unknown-linux-gnu":   {"linux", "ppc64le"},
+		"mytool-x86_64-unknown-freebsd":          {"freebsd", "x64"},
+		"mytool-x86_64-unknown-illumos":          {"sunos", "x64"},
+		"mytool-loongarch64-unknown-linux-gnu":   {"linux", "loong64"},
+		"README.md":                              {"", ""},
+		"mytool-x86_64-unknown-linux-gnu.sha256": {"linux", "x64"},
+		"my-cli_v1.0.0_checksums.txt":            {"", ""},
+		"x86_64-unknown-linux-musl/mytool":       {"linux", "x64"},
+		"mytool-aarch64-unknown-linux-musl.zip":  {"linux", "arm64"},
+		"mytool-x86_64-apple-darwin_v2":          {"darwin", "x64"},
+		"my-cli_linux_arm_6":                     {"linux", "arm"},
+		"my-cli_linux_armv7.tar.gz":              {"linux", "arm"},
+		"my-cli_linux_amd64_v3":                  {"linux", "x64"},
+		"my-cli_linux_riscv64":                   {"linux", "riscv64"},
+		"my-cli_linux_loong64":                   {"linux", "loong64"},
+	}
+	for fileName, want := range tests {
+		platform, arch := ExtractOsAndArchFromFileName(fileName)
+		if platform != want[0] || arch != want[1] {
+			t.Errorf("ExtractOsAndArchFromFileName(%q) = %q, %q, want %q, %q", fileName, platform, arch, want[0], want[1])
+		}
+	}
+}
+
+func TestExtractVariantFromFileName(t *testing.T) {
+	tests := map[string]string{
+		"my-cli_linux_arm_6":                   "6",
+		"my-cli_linux_arm_7.tar.gz":            "7",
+		"my-cli-linux-armv7":                   "7",
+		"my-cli_linux_amd64_v3":                "v3",
+		"my-cli_linux_amd64_v1.zip":            "v1",
+		"my-cli_linux_amd64":                   "",
+		"my-cli_linux_arm64":                   "",
+		"my-cli_linux_amd64_7":                 "",
+		"my-cli_linux_arm_v2":                  "",
+		"mytool-armv7-unknown-linux-gnueabihf": "7",
+		"mytool-arm-unknown-linux-gnueabihf":   "6",
+		"my-cli_linux_arm_5.0.1":               "",
+		"my-cli_linux_arm-6.1.0.tar.gz":        "",
+		"my-cli_linux_arm7":                    "",
+		"my-cli_linux_amd64_v2.1":              "",
+		"my-cli_1.2.3_linux_amd64v3.tar.gz":    "v3",
+	}
+	for fileName, want := range tests {
+		if got := ExtractVariantFromFileName(fileName); got != want {
+			t.Errorf("ExtractVariantFromFileName(%q) = %q, want %q", fileName, got, want)
+		}
+	}
+}
+
+func TestParseTargetTriple(t *testing.T) {
+	triple := ParseTargetTriple("mytool-x86_64-unknown-linux-musl.tar.gz")
+	if triple == nil {
+		t.Fatal("no target triple found")
+	}
+	want := TargetTriple{Arch: "x86_64", Vendor: "unknown", OS: "linux", ABI: "musl"}
+	if *triple != want {
+		t.Fatalf("ParseTargetTriple = %+v, want %+v", *triple, want)
+	}
+	if triple := ParseTargetTriple("aarch64-apple-darwin"); triple == nil || triple.Vendor != "apple" || triple.ABI != "" {
+		t.Fatalf("unexpected triple %+v", triple)
+	}
+	if triple := ParseTargetTriple("my-cli_linux_amd64"); triple != nil {
+		t.Fatalf("unexpected triple %+v", triple)
+	}
+}
+
+func TestExtractLibcFromFileName(t *testing.T) {
+	tests := map[string]string{
+		"mytool-x86_64-unknown-linux-musl":     LibcMusl,
+		"mytool-x86_64-unknown-linux-gnu":      LibcGlibc,
+		"mytool-armv7-unknown-linux-gnueabihf": LibcGlibc,
+		"my-cli_linux_amd64_musl":              LibcMusl,
+		"my-cli_linux_amd64-glibc":             LibcGlibc,
+		"my-cli_linux_amd64":                   "",
+		"mytool-aarch64-apple-darwin":          "",
+		"gnu-tool_linux_amd64":                 "",
+		"musl-tool_linux_amd64_glibc.tar.gz":   LibcGlibc,
+		"my-cli_linux_arm_7-musl":              LibcMusl,
+		"my-cli-musl":                          "",
+	}
+	for fileName, want := range tests {
+		if got := ExtractLibcFromFileName(fileName); got != want {
+			t.Errorf("ExtractLibcFromFileName(%q) = %q, want %q", fileName, got, want)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package helper
+
+import (
+	"regexp"
+	"strings"
+)
+
+// TargetTriple is a Rust/LLVM target triple in the form <arch>-<vendor>-<os>[-<abi>] (e.g. x86_64-unknown-linux-musl).
+type TargetTriple struct {
+	Arch   string
+	Vendor string
+	OS     string
+	ABI    string
+}
+
+var targetTripleRegexp = regexp.MustCompile("(?i)(?:^|[^a-z0-9])(x86_64|amd64|i[3-6]86|aarch64|arm64|armv[5-7][a-z]*|arm|thumbv7neon|riscv64gc|riscv64|powerpc64le|powerpc64|ppc64le|ppc64|s390x|loongarch64|mips64el|mips64|mipsel|mips)-(?:(unknown|pc|apple|sun|none|uwp|wrs|alpine|openwrt)-)?(linux|darwin|macos|windows|freebsd|netbsd|openbsd|dragonfly|illumos|solaris|android)(?:-(gnu[a-z0-9_]*|musl[a-z0-9_]*|msvc|androideabi|android|ohos))?(?:$|[^a-z0-9])")
+
+// ParseTargetTriple finds a target triple in the given file name. It returns nil if no triple was found.
+func ParseTargetTriple(fileName string) *TargetTriple {
+	match := targetTripleRegexp.FindStringSubmatch(fileName)
+	if match == nil {
+		return nil
+	}
+	return &TargetTriple{
+		Arch:   strings.ToLower(match[1]),
+		Vendor: strings.ToLower(match[2]),
+		OS:     strings.ToLower(match[3]),
+		ABI:    strings.ToLower(match[4]),
+	}
+}
+
+// GoOS returns the GOOS equivalent of the triple's operating system.
+func (t *TargetTriple) GoOS() string {
+	switch {
+	case t.OS == "macos":
+		return "darwin"
+	case t.OS == "linux" && strings.HasPrefix(t.ABI, "android"):
+		return "android"
+	}
+	return t.OS
+}
+
+// GoArch returns the GOARCH equivalent of the triple's architecture.
+func (t *TargetTriple) GoArch() string {
+	switch {
+	case t.Arch == "x86_64":
+		return "amd64"
+	case strings.HasSuffix(t.Arch, "86"):
+		return "386"
+	case t.Arch == "aarch64":
+		return "arm64"
+	case strings.HasPrefix(t.Arch, "armv"), t.Arch == "thumbv7neon":
+		return "arm"
+	case strings.HasPrefix(t.Arch, "riscv64"):
+		return "riscv64"
+	case t.Arch == "powerpc64le":
+		return "ppc64le"
+	case t.Arch == "powerpc64":
+		return "ppc64"
+	case t.Arch == "loongarch64":
+		return "loong64"
+	case t.Arch == "mips64el":
+		return "mips64le"
+	case t.Arch == "mipsel":
+		return "mipsle"
+	}
+	return t.Arch
+}
+
+// GoArm returns the GOARM equivalent of an ARM architecture (e.g. 7 for armv7). The Rust arm targets are ARMv6.
+func (t *TargetTriple) GoArm() string {
+	switch {
+	case strings.HasPrefix(t.Arch, "armv"):
+		return t.Arch[4:5]
+	case t.Arch == "thumbv7neon":
+		return "7"
+	case t.Arch == "arm":
+		return "6"
+	}
+	return ""
+}
@@ -0,0 +1,134 @@
+package pack
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha1"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// packagePrefix is the directory npm expects all files of a package tarball to be in.
+const packagePrefix = "package"
+
+// modTime is the fixed modification time npm uses for all tarball entries (1985-10-26T08:15:00Z).
+var modTime = time.Date(1985, time.October, 26, 8, 15, 0, 0, time.UTC)
+
+type Tarball struct {
+	Data []byte
+	// Shasum is the hex encoded sha1 checksum of the tarball.
+	Shasum string
+	// Integrity is the sha512 subresource integrity string of the tarball.
+	Integrity string
+}
+
+func NewTarball(data []byte) *Tarball {
+	shasum := sha1.Sum(data)
+	integrity := sha512.Sum512(data)
+	return &Tarball{
+		Data:      data,
+		Shasum:    hex.EncodeToString(shasum[:]),
+		Integrity: "sha512-" + base64.StdEncoding.EncodeToString(integrity[:]),
+	}
+}
+
+// FileName returns the file name npm pack would use for the given package (e.g. my-org-cli-1.0.0.tgz).
+func FileName(packageName, version string) string {
+	name := strings.ReplaceAll(strings.TrimPrefix(packageName, "@"), "/", "-")
+	return fmt.Sprintf("%s-%s.tgz", name, version)
+}
+
+func listFiles(dir string) ([]string, error) {
+	files := make([]string, 0)
+	err := filepath.WalkDir(dir, func(filePath string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		relPath, err := filepath.Rel(dir, filePath)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(relPath))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// Pack creates a gzip compressed npm package tarball that contains all files of the given directory.
+// The output only depends on the file names, contents and executable bits, hence the same inputs
+// always result in byte-identical tarballs.
+func Pack(dir string) (*Tarball, error) {
+	files, err := listFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+	gzWriter := gzip.NewWriter(buf)
+	tarWriter := tar.NewWriter(gzWriter)
+	for _, file := range files {
+		if err := addFile(tarWriter, dir, file); err != nil {
+			return nil, err
+		}
+	}
+	if err := tarWriter.Close(); err != nil {
+		return nil, err
+	}
+	if err := gzWriter.Close(); err != nil {
+		return nil, err
+	}
+	return NewTarball(buf.Bytes()), nil
+}
+
+func addFile(tarWriter *tar.Writer, dir, file string) (err error) {
+	f, err := os.Open(filepath.Join(dir, filepath.FromSlash(file)))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("%s is not a regular file", file)
+	}
+
+	mode := int64(0644)
+	if info.Mode().Perm()&0111 != 0 {
+		mode = 0755
+	}
+	header := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     path.Join(packagePrefix, file),
+		Size:     info.Size(),
+		Mode:     mode,
+		ModTime:  modTime,
+	}
+	if err := tarWriter.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err = io.Copy(tarWriter, f)
+	return err
+}
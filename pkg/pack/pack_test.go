@@ -0,0 +1,105 @@
+package pack
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func writeTestPackage(t *testing.T, dir string, modTime time.Time) {
+	t.Helper()
+	files := map[string]os.FileMode{
+		"package.json": 0600,
+		"README.md":    0644,
+		"my-cli":       0755,
+	}
+	for name, mode := range files {
+		filePath := filepath.Join(dir, name)
+		if err := os.WriteFile(filePath, []byte("content of "+name), mode); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chmod(filePath, mode); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(filePath, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestPackIsDeterministic(t *testing.T) {
+	dirA, dirB := t.TempDir(), t.TempDir()
+	writeTestPackage(t, dirA, time.Now())
+	writeTestPackage(t, dirB, time.Now().Add(-48*time.Hour))
+
+	tarballA, err := Pack(dirA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tarballB, err := Pack(dirB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(tarballA.Data, tarballB.Data) {
+		t.Fatal("tarballs of identical packages differ")
+	}
+	if tarballA.Integrity != tarballB.Integrity || tarballA.Shasum != tarballB.Shasum {
+		t.Fatal("integrity values of identical packages differ")
+	}
+}
+
+func TestPackEntries(t *testing.T) {
+	dir := t.TempDir()
+	writeTestPackage(t, dir, time.Now())
+	tarball, err := Pack(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gzReader, err := gzip.NewReader(bytes.NewReader(tarball.Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tarReader := tar.NewReader(gzReader)
+	wantNames := []string{"package/README.md", "package/my-cli", "package/package.json"}
+	wantModes := []int64{0644, 0755, 0644}
+	if runtime.GOOS == "windows" {
+		wantModes[1] = 0644
+	}
+	for i := 0; ; i++ {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			if i != len(wantNames) {
+				t.Fatalf("got %d entries, want %d", i, len(wantNames))
+			}
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if header.Name != wantNames[i] {
+			t.Fatalf("entry %d = %q, want %q", i, header.Name, wantNames[i])
+		}
+		if header.Mode != wantModes[i] {
+			t.Fatalf("%s mode = %o, want %o", header.Name, header.Mode, wantModes[i])
+		}
+		if !header.ModTime.Equal(modTime) || header.Uid != 0 || header.Gid != 0 {
+			t.Fatalf("%s has non-normalized header %+v", header.Name, header)
+		}
+	}
+}
+
+func TestFileName(t *testing.T) {
+	if got := FileName("@my-org/cli", "1.0.0"); got != "my-org-cli-1.0.0.tgz" {
+		t.Fatalf("FileName = %q", got)
+	}
+	if got := FileName("cli", "1.0.0"); got != "cli-1.0.0.tgz" {
+		t.Fatalf("FileName = %q", got)
+	}
+}
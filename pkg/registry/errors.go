@@ -0,0 +1,134 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+)
+
+// ErrorKind classifies registry errors.
+type ErrorKind string
+
+const (
+	ErrorKindNetwork        ErrorKind = "network"
+	ErrorKindServer         ErrorKind = "server"
+	ErrorKindRateLimited    ErrorKind = "rate-limited"
+	ErrorKindAuth           ErrorKind = "auth"
+	ErrorKindVersionExists  ErrorKind = "version-exists"
+	ErrorKindInvalidPackage ErrorKind = "invalid-package"
+	ErrorKindUnknown        ErrorKind = "unknown"
+)
+
+// Error is returned for all failed registry requests.
+type Error struct {
+	Op         string
+	Package    string
+	Version    string
+	StatusCode int
+	Kind       ErrorKind
+	Message    string
+	Err        error
+}
+
+func (e *Error) Error() string {
+	pkg := e.Package
+	if e.Version != "" {
+		pkg += "@" + e.Version
+	}
+	prefix := fmt.Sprintf("%s %s failed (%s)", e.Op, pkg, e.Kind)
+	switch {
+	case e.Err != nil:
+		return fmt.Sprintf("%s: %v", prefix, e.Err)
+	case e.StatusCode != 0:
+		return fmt.Sprintf("%s: %d %s", prefix, e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("%s: %s", prefix, e.Message)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Retryable reports whether the request may succeed if it is retried.
+func (e *Error) Retryable() bool {
+	switch e.Kind {
+	case ErrorKindNetwork, ErrorKindServer, ErrorKindRateLimited:
+		return true
+	}
+	return false
+}
+
+// IsRetryable reports whether err contains a retryable registry error.
+func IsRetryable(err error) bool {
+	var registryErr *Error
+	return errors.As(err, &registryErr) && registryErr.Retryable()
+}
+
+// KindFromStatusCode classifies a failed registry response.
+func KindFromStatusCode(statusCode int) ErrorKind {
+	switch {
+	case statusCode == http.StatusTooManyRequests:
+		return ErrorKindRateLimited
+	case statusCode == http.StatusRequestTimeout, statusCode >= 500:
+		return ErrorKindServer
+	case statusCode == http.StatusUnauthorized:
+		return ErrorKindAuth
+	case statusCode == http.StatusConflict:
+		return ErrorKindVersionExists
+	case statusCode == http.StatusForbidden:
+		// the npm registry responds with 403 if the version already exists
+		// or if the token is not allowed to publish the package
+		return ErrorKindAuth
+	case statusCode >= 400:
+		return ErrorKindInvalidPackage
+	}
+	return ErrorKindUnknown
+}
+
+// KindFromError classifies errors that occurred before a response was received.
+func KindFromError(err error) ErrorKind {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.Canceled):
+		return ErrorKindUnknown
+	case errors.As(err, &netErr),
+		errors.Is(err, io.EOF),
+		errors.Is(err, io.ErrUnexpectedEOF),
+		errors.Is(err, syscall.ECONNRESET),
+		errors.Is(err, syscall.ECONNREFUSED):
+		return ErrorKindNetwork
+	}
+	return ErrorKindUnknown
+}
+
+func newResponseError(op, name, version string, res *http.Response) *Error {
+	body, _ := io.ReadAll(res.Body)
+	message := strings.TrimSpace(string(body))
+	kind := KindFromStatusCode(res.StatusCode)
+	if kind == ErrorKindAuth && strings.Contains(message, "previously published") {
+		kind = ErrorKindVersionExists
+	}
+	return &Error{
+		Op:         op,
+		Package:    name,
+		Version:    version,
+		StatusCode: res.StatusCode,
+		Kind:       kind,
+		Message:    message,
+	}
+}
+
+func newRequestError(op, name, version string, err error) *Error {
+	return &Error{
+		Op:      op,
+		Package: name,
+		Version: version,
+		Kind:    KindFromError(err),
+		Err:     err,
+	}
+}
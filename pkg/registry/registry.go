@@ -0,0 +1,251 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/christophwitzko/npm-binary-releaser/pkg/pack"
+)
+
+// Client is a minimal npm registry client that is able to publish packages.
+type Client struct {
+	registryURL string
+	token       string
+	httpClient  *http.Client
+}
+
+// Timeouts of the registry requests. There is no overall deadline, as uploading large packages on slow
+// connections can take a long time, but the registry must respond once the request was sent.
+const (
+	DialTimeout           = 30 * time.Second
+	TLSHandshakeTimeout   = 30 * time.Second
+	ResponseHeaderTimeout = 2 * time.Minute
+)
+
+func newTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{Timeout: DialTimeout, KeepAlive: 30 * time.Second}).DialContext
+	transport.TLSHandshakeTimeout = TLSHandshakeTimeout
+	transport.ResponseHeaderTimeout = ResponseHeaderTimeout
+	return transport
+}
+
+func NewClient(registryURL, token string) *Client {
+	if !strings.HasSuffix(registryURL, "/") {
+		registryURL += "/"
+	}
+	return &Client{
+		registryURL: registryURL,
+		token:       token,
+		httpClient:  &http.Client{Transport: newTransport()},
+	}
+}
+
+type Dist struct {
+	Shasum    string `json:"shasum"`
+	Integrity string `json:"integrity"`
+	Tarball   string `json:"tarball"`
+}
+
+type Attachment struct {
+	ContentType string `json:"content_type"`
+	Data        string `json:"data"`
+	Length      int    `json:"length"`
+}
+
+// PublishDocument is the body of the PUT request the npm CLI sends to publish a new package version.
+type PublishDocument struct {
+	ID          string                    `json:"_id"`
+	Name        string                    `json:"name"`
+	Description string                    `json:"description,omitempty"`
+	DistTags    map[string]string         `json:"dist-tags"`
+	Versions    map[string]map[string]any `json:"versions"`
+	Access      string                    `json:"access,omitempty"`
+	Attachments map[string]Attachment     `json:"_attachments"`
+}
+
+func manifestString(manifest map[string]any, key string) string {
+	value, _ := manifest[key].(string)
+	return value
+}
+
+func unscopedName(name string) string {
+	if _, after, found := strings.Cut(name, "/"); found {
+		return after
+	}
+	return name
+}
+
+func (c *Client) packageURL(name string) string {
+	return c.registryURL + url.PathEscape(name)
+}
+
+func (c *Client) tarballURL(name, version string) string {
+	return fmt.Sprintf("%s%s/-/%s-%s.tgz", c.registryURL, name, unscopedName(name), version)
+}
+
+// NewPublishDocument creates the publish document for the given package.json manifest and tarball.
+func (c *Client) NewPublishDocument(manifest map[string]any, tarball *pack.Tarball, distTag string) (*PublishDocument, error) {
+	name := manifestString(manifest, "name")
+	version := manifestString(manifest, "version")
+	if name == "" || version == "" {
+		return nil, &Error{Op: "publish", Package: name, Version: version, Kind: ErrorKindInvalidPackage, Message: "package name or version is missing"}
+	}
+
+	versionManifest := make(map[string]any, len(manifest)+2)
+	for k, v := range manifest {
+		versionManifest[k] = v
+	}
+	versionManifest["_id"] = fmt.Sprintf("%s@%s", name, version)
+	versionManifest["dist"] = Dist{
+		Shasum:    tarball.Shasum,
+		Integrity: tarball.Integrity,
+		Tarball:   c.tarballURL(name, version),
+	}
+
+	access := ""
+	if publishConfig, ok := manifest["publishConfig"].(map[string]any); ok {
+		access, _ = publishConfig["access"].(string)
+	}
+
+	return &PublishDocument{
+		ID:          name,
+		Name:        name,
+		Description: manifestString(manifest, "description"),
+		DistTags:    map[string]string{distTag: version},
+		Versions:    map[string]map[string]any{version: versionManifest},
+		Access:      access,
+		Attachments: map[string]Attachment{
+			fmt.Sprintf("%s-%s.tgz", name, version): {
+				ContentType: "application/octet-stream",
+				Data:        base64.StdEncoding.EncodeToString(tarball.Data),
+				Length:      len(tarball.Data),
+			},
+		},
+	}, nil
+}
+
+// Packument is the registry document that contains all published versions of a package.
+type Packument struct {
+	Name     string                      `json:"name"`
+	DistTags map[string]string           `json:"dist-tags"`
+	Versions map[string]PackumentVersion `json:"versions"`
+}
+
+type PackumentVersion struct {
+	Version              string            `json:"version"`
+	OptionalDependencies map[string]string `json:"optionalDependencies,omitempty"`
+	Dist                 Dist              `json:"dist"`
+}
+
+var ErrPackageNotFound = errors.New("package not found")
+
+func (c *Client) newRequest(ctx context.Context, method, url string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	return req, nil
+}
+
+// GetPackument fetches the packument of the given package. It returns ErrPackageNotFound if the package does not exist.
+func (c *Client) GetPackument(ctx context.Context, name string) (*Packument, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, c.packageURL(name), nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, newRequestError("fetch", name, "", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotFound {
+		return nil, ErrPackageNotFound
+	}
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return nil, newResponseError("fetch", name, "", res)
+	}
+	packument := &Packument{}
+	if err := json.NewDecoder(res.Body).Decode(packument); err != nil {
+		return nil, newRequestError("fetch", name, "", err)
+	}
+	return packument, nil
+}
+
+// Publish uploads the given package tarball to the registry and tags the version with the dist-tag.
+func (c *Client) Publish(ctx context.Context, manifest map[string]any, tarball *pack.Tarball, distTag string) error {
+	doc, err := c.NewPublishDocument(manifest, tarball, distTag)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	req, err := c.newRequest(ctx, http.MethodPut, c.packageURL(doc.Name), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	version := manifestString(manifest, "version")
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return newRequestError("publish", doc.Name, version, err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return newResponseError("publish", doc.Name, version, res)
+	}
+	_, _ = io.Copy(io.Discard, res.Body)
+	return nil
+}
+
+func (c *Client) distTagURL(name, tag string) string {
+	return fmt.Sprintf("%s-/package/%s/dist-tags/%s", c.registryURL, url.PathEscape(name), url.PathEscape(tag))
+}
+
+func (c *Client) doDistTagRequest(ctx context.Context, method, name, tag string, body io.Reader) error {
+	req, err := c.newRequest(ctx, method, c.distTagURL(name, tag), body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return newRequestError("dist-tag", name, "", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return newResponseError("dist-tag", name, "", res)
+	}
+	_, _ = io.Copy(io.Discard, res.Body)
+	return nil
+}
+
+// SetDistTag points the dist-tag of the package to the given version.
+func (c *Client) SetDistTag(ctx context.Context, name, tag, version string) error {
+	body, err := json.Marshal(version)
+	if err != nil {
+		return err
+	}
+	return c.doDistTagRequest(ctx, http.MethodPut, name, tag, bytes.NewReader(body))
+}
+
+// RemoveDistTag removes the dist-tag from the package.
+func (c *Client) RemoveDistTag(ctx context.Context, name, tag string) error {
+	return c.doDistTagRequest(ctx, http.MethodDelete, name, tag, nil)
+}
@@ -0,0 +1,139 @@
+package registry
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/christophwitzko/npm-binary-releaser/pkg/pack"
+)
+
+func TestPublish(t *testing.T) {
+	var gotPath, gotAuth string
+	var gotDoc PublishDocument
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			t.Errorf("method = %s, want PUT", r.Method)
+		}
+		gotPath = r.URL.EscapedPath()
+		gotAuth = r.Header.Get("Authorization")
+		if err := json.NewDecoder(r.Body).Decode(&gotDoc); err != nil {
+			t.Error(err)
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	tarball := pack.NewTarball([]byte("tarball-content"))
+	manifest := map[string]any{
+		"name":          "@my-org/cli",
+		"version":       "1.2.3",
+		"publishConfig": map[string]any{"access": "public"},
+	}
+	client := NewClient(srv.URL, "secret")
+	if err := client.Publish(context.Background(), manifest, tarball, "latest"); err != nil {
+		t.Fatal(err)
+	}
+
+	if gotPath != "/@my-org%2Fcli" {
+		t.Fatalf("path = %q, want %q", gotPath, "/@my-org%2Fcli")
+	}
+	if gotAuth != "Bearer secret" {
+		t.Fatalf("authorization = %q, want %q", gotAuth, "Bearer secret")
+	}
+	if gotDoc.DistTags["latest"] != "1.2.3" {
+		t.Fatalf("dist-tags = %v", gotDoc.DistTags)
+	}
+	if gotDoc.Access != "public" {
+		t.Fatalf("access = %q, want %q", gotDoc.Access, "public")
+	}
+	attachment, ok := gotDoc.Attachments["@my-org/cli-1.2.3.tgz"]
+	if !ok {
+		t.Fatalf("attachment @my-org/cli-1.2.3.tgz missing: %v", gotDoc.Attachments)
+	}
+	if data, _ := base64.StdEncoding.DecodeString(attachment.Data); string(data) != string(tarball.Data) || attachment.Length != len(tarball.Data) {
+		t.Fatalf("unexpected attachment %+v", attachment)
+	}
+	dist, _ := gotDoc.Versions["1.2.3"]["dist"].(map[string]any)
+	if dist["shasum"] != "3c4fb10163dc33fd83b588fe36af9aa5efba2985" {
+		t.Fatalf("unexpected shasum %v", dist["shasum"])
+	}
+	if dist["tarball"] != srv.URL+"/@my-org/cli/-/cli-1.2.3.tgz" {
+		t.Fatalf("unexpected tarball url %v", dist["tarball"])
+	}
+}
+
+func TestGetPackumentNotFound(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	if _, err := NewClient(srv.URL, "").GetPackument(context.Background(), "cli"); !errors.Is(err, ErrPackageNotFound) {
+		t.Fatalf("err = %v, want %v", err, ErrPackageNotFound)
+	}
+}
+
+func TestPublishErrorClassification(t *testing.T) {
+	tests := []struct {
+		status    int
+		body      string
+		kind      ErrorKind
+		retryable bool
+	}{
+		{http.StatusServiceUnavailable, "unavailable", ErrorKindServer, true},
+		{http.StatusTooManyRequests, "slow down", ErrorKindRateLimited, true},
+		{http.StatusUnauthorized, "unauthorized", ErrorKindAuth, false},
+		{http.StatusForbidden, "You cannot publish over the previously published versions: 1.0.0.", ErrorKindVersionExists, false},
+		{http.StatusBadRequest, "invalid package", ErrorKindInvalidPackage, false},
+	}
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, tt.body, tt.status)
+			}))
+			defer srv.Close()
+
+			manifest := map[string]any{"name": "cli", "version": "1.0.0"}
+			err := NewClient(srv.URL, "").Publish(context.Background(), manifest, pack.NewTarball([]byte("x")), "latest")
+			var registryErr *Error
+			if !errors.As(err, &registryErr) {
+				t.Fatalf("err = %v, want *Error", err)
+			}
+			if registryErr.Kind != tt.kind || registryErr.StatusCode != tt.status || IsRetryable(err) != tt.retryable {
+				t.Fatalf("unexpected error %+v", registryErr)
+			}
+		})
+	}
+}
+
+func TestPublishNetworkErrorIsRetryable(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+
+	manifest := map[string]any{"name": "cli", "version": "1.0.0"}
+	err := NewClient(srv.URL, "").Publish(context.Background(), manifest, pack.NewTarball([]byte("x")), "latest")
+	if !IsRetryable(err) {
+		t.Fatalf("err = %v, want retryable network error", err)
+	}
+}
+
+func TestPublishTimeoutIsRetryable(t *testing.T) {
+	done := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer srv.Close()
+	defer close(done)
+
+	client := NewClient(srv.URL, "")
+	client.httpClient.Transport.(*http.Transport).ResponseHeaderTimeout = 10 * time.Millisecond
+	manifest := map[string]any{"name": "cli", "version": "1.0.0"}
+	err := client.Publish(context.Background(), manifest, pack.NewTarball([]byte("x")), "latest")
+	if !IsRetryable(err) {
+		t.Fatalf("err = %v, want retryable network error", err)
+	}
+}
@@ -0,0 +1,558 @@
+package releaser
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/christophwitzko/npm-binary-releaser/pkg/archive"
+	"github.com/christophwitzko/npm-binary-releaser/pkg/config"
+	"github.com/christophwitzko/npm-binary-releaser/pkg/goreleaser"
+	"github.com/christophwitzko/npm-binary-releaser/pkg/helper"
+)
+
+// findBinaryFiles discovers all binaries in the input directory. Archives are extracted into workDir.
+func findBinaryFiles(c *config.Config, logger Logger, workDir string) ([]*helper.BinFile, error) {
+	verifier, err := newChecksumVerifier(c, logger)
+	if err != nil {
+		return nil, err
+	}
+	if c.GoReleaserArtifacts {
+		return findGoReleaserBinaryFiles(c, logger, verifier)
+	}
+	if len(c.Platforms) > 0 {
+		return findPlatformBinaryFiles(c, logger, workDir, verifier)
+	}
+	logger.Printf("reading binary files from: %s", c.InputBinDirPath)
+	files, err := os.ReadDir(c.InputBinDirPath)
+	if err != nil {
+		return nil, err
+	}
+
+	foundFiles := make([]*helper.BinFile, 0, len(files))
+	for _, file := range files {
+		logger.Printf("checking file %s", file.Name())
+		fPath := path.Join(c.InputBinDirPath, file.Name())
+		if !file.IsDir() && archive.IsArchive(file.Name()) {
+			// the archive is verified before it is extracted
+			if !verifier.verify(fPath, c.InputBinDirPath) {
+				continue
+			}
+			binFiles, err := findArchiveBinaryFiles(c, logger, file.Name(), fPath, workDir)
+			if err != nil {
+				return nil, err
+			}
+			foundFiles = append(foundFiles, binFiles...)
+			continue
+		}
+		binary := matchBinary(c, file.Name())
+		if binary == nil {
+			logger.Printf("skipping %s, it does not match any binary", file.Name())
+			continue
+		}
+		if file.IsDir() {
+			execPath, err := helper.FindFirstExecutableFileInDir(fPath)
+			if err != nil {
+				logger.Printf("could not find bin file in dir %s %v", fPath, err)
+				continue
+			}
+			fPath = execPath
+		}
+		binFiles, err := detectPlatforms(c, logger, file.Name(), fPath)
+		if err != nil {
+			return nil, err
+		}
+		if len(binFiles) > 0 && !verifier.verify(fPath, c.InputBinDirPath) {
+			continue
+		}
+		for _, binFile := range binFiles {
+			binFile.BinName = binary.Name
+			binFile.Source = fPath
+		}
+		foundFiles = append(foundFiles, binFiles...)
+	}
+
+	if err := verifier.err(); err != nil {
+		return nil, err
+	}
+	if len(foundFiles) == 0 {
+		return nil, fmt.Errorf("no binary files found at %s", c.InputBinDirPath)
+	}
+	return selectVariants(c, logger, foundFiles)
+}
+
+// findGoReleaserBinaryFiles uses the binaries listed in GoReleaser's artifacts.json.
+// If a checksums file is configured, the binaries must be listed in it (e.g. by using GoReleaser's checksum.ids).
+func findGoReleaserBinaryFiles(c *config.Config, logger Logger, verifier *checksumVerifier) ([]*helper.BinFile, error) {
+	logger.Printf("reading GoReleaser artifacts from: %s", c.InputBinDirPath)
+	artifacts, err := goreleaser.ReadArtifacts(c.InputBinDirPath)
+	if err != nil {
+		return nil, err
+	}
+	binNames := make(map[string]bool)
+	for _, binary := range c.Binaries {
+		binNames[binary.Name] = true
+	}
+	binaries := make([]*goreleaser.Artifact, 0, len(artifacts))
+	universalBinaries := make([]*goreleaser.Artifact, 0)
+	hasBinName := c.HasMultipleBinaries()
+	for _, artifact := range artifacts {
+		switch artifact.Type {
+		case goreleaser.ArtifactTypeBinary:
+			binaries = append(binaries, artifact)
+		case goreleaser.ArtifactTypeUniversalBinary:
+			universalBinaries = append(universalBinaries, artifact)
+		default:
+			continue
+		}
+		hasBinName = hasBinName || binNames[artifact.BinaryName()]
+	}
+	binaries = append(binaries, expandUniversalBinaries(logger, binaries, universalBinaries)...)
+
+	foundFiles := make([]*helper.BinFile, 0, len(binaries))
+	for _, artifact := range binaries {
+		// if the project builds multiple binaries, only use the ones named like the configured binaries
+		if hasBinName && !binNames[artifact.BinaryName()] {
+			logger.Printf("skipping artifact %s (%s)", artifact.Name, artifact.Path)
+			continue
+		}
+		binName := c.BinName
+		if c.HasMultipleBinaries() {
+			binName = artifact.BinaryName()
+		}
+		platform, arch := helper.NodePlatformAndArch(artifact.Goos, artifact.Goarch)
+		logger.Printf("found artifact %s for %s-%s", artifact.Path, platform, arch)
+		artifactPath := goreleaser.ResolveArtifactPath(c.InputBinDirPath, artifact)
+		if !verifier.verify(artifactPath, c.InputBinDirPath) {
+			continue
+		}
+		// the checksum in artifacts.json comes from the same dist directory, hence it is only cross-checked
+		if checksum := artifact.Checksum(); verifier != nil && checksum != "" {
+			if err := helper.VerifyChecksum(artifactPath, checksum); err != nil {
+				verifier.errs = append(verifier.errs, fmt.Errorf("%s: %w", goreleaser.ArtifactsFileName, err))
+				continue
+			}
+		}
+		foundFiles = append(foundFiles, &helper.BinFile{
+			BinName:  binName,
+			Platform: platform,
+			Arch:     arch,
+			Path:     artifactPath,
+			Source:   artifactPath,
+			FileName: artifact.Name,
+			Variant:  helper.NormalizeVariant(arch, artifact.Goarm+artifact.Goamd64),
+		})
+	}
+	if err := verifier.err(); err != nil {
+		return nil, err
+	}
+	if len(foundFiles) == 0 {
+		return nil, fmt.Errorf("no binary artifacts found in %s", path.Join(c.InputBinDirPath, goreleaser.ArtifactsFileName))
+	}
+	return selectVariants(c, logger, foundFiles)
+}
+
+// expandUniversalBinaries returns an amd64 and an arm64 artifact for every universal binary. If GoReleaser kept
+// the single arch binaries (universal_binaries.replace: false), they are used instead.
+func expandUniversalBinaries(logger Logger, binaries, universalBinaries []*goreleaser.Artifact) []*goreleaser.Artifact {
+	expanded := make([]*goreleaser.Artifact, 0, len(universalBinaries)*2)
+	for _, universal := range universalBinaries {
+		for _, goarch := range []string{"amd64", "arm64"} {
+			exists := slices.ContainsFunc(binaries, func(artifact *goreleaser.Artifact) bool {
+				return artifact.Goos == universal.Goos && artifact.Goarch == goarch && artifact.BinaryName() == universal.BinaryName()
+			})
+			if exists {
+				logger.Printf("skipping universal binary %s for %s, the %s binary is used", universal.Path, goarch, goarch)
+				continue
+			}
+			artifact := *universal
+			artifact.Goarch = goarch
+			artifact.Goarm = ""
+			artifact.Goamd64 = ""
+			expanded = append(expanded, &artifact)
+		}
+	}
+	return expanded
+}
+
+// matchBinary returns the configured binary whose match pattern matches the file name.
+// A binary without pattern matches all files.
+func matchBinary(c *config.Config, fileName string) *config.Binary {
+	for i, binary := range c.Binaries {
+		if binary.Match == "" {
+			return &c.Binaries[i]
+		}
+		if ok, _ := path.Match(binary.Match, fileName); ok {
+			return &c.Binaries[i]
+		}
+	}
+	return nil
+}
+
+// findArchiveBinaryFiles extracts the archive into a new directory in workDir and detects the platform of all
+// configured binaries in it. The archive files that should be included in the package are listed relative to
+// the binary.
+func findArchiveBinaryFiles(c *config.Config, logger Logger, fileName, archivePath, workDir string) ([]*helper.BinFile, error) {
+	extractDir, err := os.MkdirTemp(workDir, "archive-")
+	if err != nil {
+		return nil, err
+	}
+	logger.Printf("extracting archive %s", archivePath)
+	if err := archive.Extract(archivePath, extractDir); err != nil {
+		return nil, fmt.Errorf("could not extract archive %s: %w", archivePath, err)
+	}
+
+	execPaths := make(map[string]string)
+	for _, binary := range c.Binaries {
+		execPath, err := findBinaryInDir(c, extractDir, binary.Name)
+		if err != nil {
+			logger.Printf("could not find bin file %s in archive %s: %v", binary.Name, archivePath, err)
+			continue
+		}
+		execPaths[binary.Name] = execPath
+	}
+
+	foundFiles := make([]*helper.BinFile, 0, len(execPaths))
+	for _, binary := range c.Binaries {
+		execPath, ok := execPaths[binary.Name]
+		if !ok {
+			continue
+		}
+		extraFiles, err := findArchiveExtraFiles(c, execPath, execPaths)
+		if err != nil {
+			return nil, err
+		}
+		binFiles, err := detectPlatforms(c, logger, fileName, execPath)
+		if err != nil {
+			return nil, err
+		}
+		for _, binFile := range binFiles {
+			binFile.BinName = binary.Name
+			binFile.Source = archivePath
+			binFile.ExtraFiles = extraFiles
+		}
+		foundFiles = append(foundFiles, binFiles...)
+	}
+	return foundFiles, nil
+}
+
+// findBinaryInDir searches dir recursively for the binary. Multiple binaries are only found by their name.
+func findBinaryInDir(c *config.Config, dir, binName string) (string, error) {
+	if c.HasMultipleBinaries() {
+		return helper.FindExecutableFileByName(dir, binName)
+	}
+	return helper.FindExecutableFile(dir, c.ArchiveBinaryPath, binName)
+}
+
+// findPlatformBinaryFiles uses the explicit platform mapping of the config. A platform path can be a binary,
+// a directory or an archive. Other than auto-detection, every configured binary must be found.
+func findPlatformBinaryFiles(c *config.Config, logger Logger, workDir string, verifier *checksumVerifier) ([]*helper.BinFile, error) {
+	foundFiles := make([]*helper.BinFile, 0, len(c.Platforms)*len(c.Binaries))
+	for _, p := range c.Platforms {
+		logger.Printf("reading %s binaries from: %s", p.Target(), p.Path)
+		info, err := os.Stat(p.Path)
+		if err != nil {
+			return nil, fmt.Errorf("platform %s: %w", p.Target(), err)
+		}
+		// files and archives are verified before they are used, the binaries of directories are verified below
+		baseDir := filepath.Dir(p.Path)
+		if !info.IsDir() && !verifier.verify(p.Path, baseDir) {
+			return nil, verifier.err()
+		}
+		dir := p.Path
+		var extraFiles map[string][]string
+		switch {
+		case info.IsDir():
+		case archive.IsArchive(p.Path):
+			dir, err = os.MkdirTemp(workDir, "archive-")
+			if err != nil {
+				return nil, err
+			}
+			logger.Printf("extracting archive %s", p.Path)
+			if err := archive.Extract(p.Path, dir); err != nil {
+				return nil, fmt.Errorf("platform %s: %w", p.Target(), err)
+			}
+			extraFiles = make(map[string][]string)
+		case c.HasMultipleBinaries():
+			return nil, fmt.Errorf("platform %s: path must be a directory or an archive to release multiple binaries", p.Target())
+		default:
+			dir = ""
+		}
+
+		execPaths := make(map[string]string)
+		for _, binary := range c.Binaries {
+			if dir == "" {
+				execPaths[binary.Name] = p.Path
+				continue
+			}
+			execPath, err := findBinaryInDir(c, dir, binary.Name)
+			if err != nil {
+				return nil, fmt.Errorf("platform %s: could not find %s in %s: %w", p.Target(), binary.Name, p.Path, err)
+			}
+			if info.IsDir() && !verifier.verify(execPath, baseDir) {
+				return nil, verifier.err()
+			}
+			execPaths[binary.Name] = execPath
+		}
+		if extraFiles != nil {
+			for _, binary := range c.Binaries {
+				if extraFiles[binary.Name], err = findArchiveExtraFiles(c, execPaths[binary.Name], execPaths); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		for _, binary := range c.Binaries {
+			execPath := execPaths[binary.Name]
+			binInfo, err := helper.ReadBinaryInfo(execPath)
+			if err != nil && !errors.Is(err, helper.ErrUnknownBinaryFormat) {
+				logger.Printf("could not read binary header of %s: %v", execPath, err)
+			}
+			if err := checkPlatform(c, logger, filepath.Base(execPath), p.OS, p.CPU, binInfo); err != nil {
+				return nil, err
+			}
+			source := p.Path
+			if info.IsDir() {
+				source = execPath
+			}
+			foundFiles = append(foundFiles, &helper.BinFile{
+				BinName:    binary.Name,
+				Platform:   p.OS,
+				Arch:       p.CPU,
+				Libc:       p.Libc,
+				Path:       execPath,
+				Source:     source,
+				FileName:   filepath.Base(execPath),
+				ExtraFiles: extraFiles[binary.Name],
+			})
+		}
+	}
+	return foundFiles, nil
+}
+
+// findArchiveExtraFiles returns the extra files next to the binary, excluding all binaries of the archive.
+func findArchiveExtraFiles(c *config.Config, execPath string, execPaths map[string]string) ([]string, error) {
+	dir := filepath.Dir(execPath)
+	exclude := make([]string, 0, len(execPaths))
+	for _, p := range execPaths {
+		if rel, err := filepath.Rel(dir, p); err == nil {
+			exclude = append(exclude, filepath.ToSlash(rel))
+		}
+	}
+	return helper.FindExtraFiles(dir, c.ArchiveExtraFiles, exclude...)
+}
+
+// selectVariants keeps a single variant (GOARM or GOAMD64) per binary and target according to the variant
+// policy, because npm can only select packages by cpu. Files without variant use Go's default variant.
+func selectVariants(c *config.Config, logger Logger, files []*helper.BinFile) ([]*helper.BinFile, error) {
+	variant := func(file *helper.BinFile) string {
+		if file.Variant != "" {
+			return file.Variant
+		}
+		return helper.DefaultVariant(file.Arch)
+	}
+	keys := make([]string, 0, len(files))
+	filesPerKey := make(map[string][]*helper.BinFile)
+	for _, file := range files {
+		key := file.BinName + "/" + file.Target()
+		if _, ok := filesPerKey[key]; !ok {
+			keys = append(keys, key)
+		}
+		filesPerKey[key] = append(filesPerKey[key], file)
+	}
+
+	selected := make([]*helper.BinFile, 0, len(files))
+	errs := make([]error, 0)
+	for _, key := range keys {
+		keyFiles := filesPerKey[key]
+		variants := make(map[string]bool)
+		for _, file := range keyFiles {
+			variants[variant(file)] = true
+		}
+		// duplicates of the same variant are reported when the packages are planned
+		if len(variants) < 2 || len(variants) < len(keyFiles) {
+			selected = append(selected, keyFiles...)
+			continue
+		}
+		descriptions := make([]string, 0, len(keyFiles))
+		for _, file := range keyFiles {
+			descriptions = append(descriptions, fmt.Sprintf("%s (%s)", variant(file), file.Source))
+		}
+		file := keyFiles[0]
+		if c.VariantPolicy == config.VariantPolicyError {
+			errs = append(errs, fmt.Errorf("multiple variants of %s for %s: %s", file.BinName, file.Target(), strings.Join(descriptions, ", ")))
+			continue
+		}
+		for _, other := range keyFiles[1:] {
+			lower := variant(other) < variant(file)
+			if lower == (c.VariantPolicy == config.VariantPolicyLowest) {
+				file = other
+			}
+		}
+		logger.Printf("found variants %s of %s for %s, releasing %s (%s policy)", strings.Join(descriptions, ", "), file.BinName, file.Target(), variant(file), c.VariantPolicy)
+		selected = append(selected, file)
+	}
+	return selected, errors.Join(errs...)
+}
+
+// resolveLibcVariants keeps the libc of Linux binaries only if there are multiple binaries for the
+// same platform and arch. A single (e.g. statically linked musl) binary is released for all Linux systems.
+func resolveLibcVariants(logger Logger, files []*helper.BinFile) {
+	filesPerTarget := make(map[string]int)
+	for _, file := range files {
+		filesPerTarget[file.BinName+"/"+file.Platform+"-"+file.Arch]++
+	}
+	for _, file := range files {
+		if file.Libc == "" || filesPerTarget[file.BinName+"/"+file.Platform+"-"+file.Arch] > 1 {
+			continue
+		}
+		logger.Printf("%s is the only %s-%s binary, releasing it for all libc variants", file.FileName, file.Platform, file.Arch)
+		file.Libc = ""
+	}
+}
+
+func detectLibc(fileName, platform string, binInfo *helper.BinaryInfo) string {
+	if platform != "linux" {
+		return ""
+	}
+	if libc := helper.ExtractLibcFromFileName(fileName); libc != "" {
+		return libc
+	}
+	if binInfo != nil {
+		return binInfo.Libc
+	}
+	return ""
+}
+
+// checkPlatform compares the expected platform and arch with the executable header. A mismatch is only
+// an error if FailOnPlatformMismatch is set.
+func checkPlatform(c *config.Config, logger Logger, fileName, platform, arch string, binInfo *helper.BinaryInfo) error {
+	if binInfo == nil || binInfo.Matches(platform, arch) {
+		return nil
+	}
+	msg := fmt.Sprintf("%s: expected %s-%s but binary header indicates %s-%s", fileName, platform, arch, binInfo.DefaultPlatform(), strings.Join(binInfo.Archs, ","))
+	if c.FailOnPlatformMismatch {
+		return errors.New(msg)
+	}
+	logger.Printf("warning: %s", msg)
+	return nil
+}
+
+// detectPlatforms determines the platform and arch of a binary file from its name and its
+// executable header. A universal binary without arch in its name results in one BinFile per arch.
+func detectPlatforms(c *config.Config, logger Logger, fileName, fPath string) ([]*helper.BinFile, error) {
+	platform, arch := helper.ExtractOsAndArchFromFileName(fileName)
+	binInfo, err := helper.ReadBinaryInfo(fPath)
+	if err != nil && !errors.Is(err, helper.ErrUnknownBinaryFormat) {
+		logger.Printf("could not read binary header of %s: %v", fPath, err)
+	}
+
+	if platform != "" && arch != "" {
+		if err := checkPlatform(c, logger, fileName, platform, arch, binInfo); err != nil {
+			return nil, err
+		}
+		return []*helper.BinFile{{
+			Platform: platform,
+			Arch:     arch,
+			Libc:     detectLibc(fileName, platform, binInfo),
+			Path:     fPath,
+			FileName: fileName,
+			Variant:  helper.ExtractVariantFromFileName(fileName),
+		}}, nil
+	}
+
+	if binInfo == nil {
+		logger.Printf("no os/arch found for %s", fileName)
+		return nil, nil
+	}
+	binFiles := make([]*helper.BinFile, 0, len(binInfo.Archs))
+	for _, binArch := range binInfo.Archs {
+		logger.Printf("detected %s-%s from binary header of %s", binInfo.DefaultPlatform(), binArch, fileName)
+		binFiles = append(binFiles, &helper.BinFile{
+			Platform: binInfo.DefaultPlatform(),
+			Arch:     binArch,
+			Libc:     detectLibc(fileName, binInfo.DefaultPlatform(), binInfo),
+			Path:     fPath,
+			FileName: fileName,
+		})
+	}
+	return binFiles, nil
+}
+
+// checksumVerifier verifies input files against the configured checksums file before they are used. A nil
+// verifier accepts all files.
+type checksumVerifier struct {
+	logger    Logger
+	checksums map[string]string
+	dir       string
+	errs      []error
+}
+
+func newChecksumVerifier(c *config.Config, logger Logger) (*checksumVerifier, error) {
+	if c.ChecksumsFile == "" {
+		return nil, nil
+	}
+	logger.Printf("verifying checksums using %s", c.ChecksumsFile)
+	checksums, err := helper.ReadChecksumsFile(c.ChecksumsFile)
+	if err != nil {
+		return nil, err
+	}
+	return &checksumVerifier{logger: logger, checksums: checksums, dir: filepath.Dir(c.ChecksumsFile)}, nil
+}
+
+// lookup returns the checksum of the file, which is listed relative to the checksums file or to baseDir.
+func (v *checksumVerifier) lookup(filePath, baseDir string) (string, bool) {
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return "", false
+	}
+	for _, dir := range []string{v.dir, baseDir} {
+		absDir, err := filepath.Abs(dir)
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(absDir, absPath)
+		if err != nil {
+			continue
+		}
+		if checksum, ok := v.checksums[filepath.ToSlash(rel)]; ok {
+			return checksum, true
+		}
+	}
+	return "", false
+}
+
+// verify looks up the checksum of the file and verifies it. It returns false if the file must not be used,
+// the error is reported by err.
+func (v *checksumVerifier) verify(filePath, baseDir string) bool {
+	if v == nil {
+		return true
+	}
+	checksum, ok := v.lookup(filePath, baseDir)
+	if !ok {
+		v.errs = append(v.errs, fmt.Errorf("no checksum found for %s", filePath))
+		return false
+	}
+	return v.verifyChecksum(filePath, checksum)
+}
+
+func (v *checksumVerifier) verifyChecksum(filePath, checksum string) bool {
+	if err := helper.VerifyChecksum(filePath, checksum); err != nil {
+		v.errs = append(v.errs, err)
+		return false
+	}
+	v.logger.Printf("checksum of %s is valid", filePath)
+	return true
+}
+
+// err returns all verification errors.
+func (v *checksumVerifier) err() error {
+	if v == nil {
+		return nil
+	}
+	return errors.Join(v.errs...)
+}
@@ -0,0 +1,108 @@
+package releaser
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/christophwitzko/npm-binary-releaser/pkg/config"
+	"github.com/christophwitzko/npm-binary-releaser/pkg/registry"
+)
+
+// releasedPackageNames returns the platform packages of the released version (taken from the optional
+// dependencies of the main package) followed by the main package.
+func releasedPackageNames(client *registry.Client, c *config.Config) ([]string, error) {
+	mainPackageName := c.MainPackageName()
+	packument, err := client.GetPackument(context.Background(), mainPackageName)
+	if err != nil {
+		return nil, err
+	}
+	version, ok := packument.Versions[c.PackageVersion]
+	if !ok {
+		return nil, fmt.Errorf("%s@%s is not published", mainPackageName, c.PackageVersion)
+	}
+	names := make([]string, 0, len(version.OptionalDependencies)+1)
+	for name := range version.OptionalDependencies {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return append(names, mainPackageName), nil
+}
+
+func validateDistTagConfig(c *config.Config, tag string) error {
+	if c.PackageName == "" {
+		c.PackageName = c.BinName
+	}
+	if c.PackageName == "" {
+		return fmt.Errorf("name is missing")
+	}
+	if tag == "" {
+		return fmt.Errorf("dist-tag is missing")
+	}
+	return config.ValidateDistTag(tag)
+}
+
+// SetDistTag points the dist-tag of the main package and all platform packages to the configured version.
+func SetDistTag(c *config.Config, logger Logger, tag string) error {
+	if err := validateDistTagConfig(c, tag); err != nil {
+		return err
+	}
+	if c.PackageVersion == "" {
+		return fmt.Errorf("package version is missing")
+	}
+	version, err := config.NormalizeVersion(c.PackageVersion)
+	if err != nil {
+		return err
+	}
+	c.PackageVersion = version
+	client, err := newRegistryClient(c)
+	if err != nil {
+		return err
+	}
+	names, err := releasedPackageNames(client, c)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		logger.Printf("[%s] setting dist-tag %s to %s", name, tag, c.PackageVersion)
+		if err := client.SetDistTag(context.Background(), name, tag, c.PackageVersion); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RemoveDistTag removes the dist-tag from the main package and all platform packages of the configured version.
+func RemoveDistTag(c *config.Config, logger Logger, tag string) error {
+	if err := validateDistTagConfig(c, tag); err != nil {
+		return err
+	}
+	if tag == config.DefaultDistTag {
+		return fmt.Errorf("the %s dist-tag cannot be removed", config.DefaultDistTag)
+	}
+	client, err := newRegistryClient(c)
+	if err != nil {
+		return err
+	}
+	if c.PackageVersion == "" {
+		packument, err := client.GetPackument(context.Background(), c.MainPackageName())
+		if err != nil {
+			return err
+		}
+		c.PackageVersion = packument.DistTags[tag]
+		if c.PackageVersion == "" {
+			return fmt.Errorf("dist-tag %s does not exist on %s", tag, c.MainPackageName())
+		}
+	}
+	names, err := releasedPackageNames(client, c)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		logger.Printf("[%s] removing dist-tag %s", name, tag)
+		if err := client.RemoveDistTag(context.Background(), name, tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
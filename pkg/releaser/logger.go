@@ -2,6 +2,7 @@ package releaser
 
 import (
 	"bufio"
+	"fmt"
 	"io"
 )
 
@@ -10,14 +11,48 @@ type Logger interface {
 	Printf(format string, v ...any)
 }
 
-func prefixedWriter(logger Logger, p string) io.Writer {
+type prefixedLogger struct {
+	logger Logger
+	prefix string
+}
+
+// newPrefixedLogger returns a logger that prefixes every line with [prefix].
+func newPrefixedLogger(logger Logger, prefix string) Logger {
+	return &prefixedLogger{logger: logger, prefix: prefix}
+}
+
+func (l *prefixedLogger) Println(v ...any) {
+	l.logger.Printf("[%s] %s", l.prefix, fmt.Sprint(v...))
+}
+
+func (l *prefixedLogger) Printf(format string, v ...any) {
+	l.logger.Printf("[%s] %s", l.prefix, fmt.Sprintf(format, v...))
+}
+
+// lineLogWriter logs every written line. Close must be called to log the last line and to stop the goroutine.
+type lineLogWriter struct {
+	*io.PipeWriter
+	done chan struct{}
+}
+
+func (w *lineLogWriter) Close() error {
+	err := w.PipeWriter.Close()
+	<-w.done
+	return err
+}
+
+func prefixedWriter(logger Logger, p string) io.WriteCloser {
 	reader, writer := io.Pipe()
 	lineScanner := bufio.NewScanner(reader)
+	done := make(chan struct{})
 	go func() {
+		defer close(done)
 		for lineScanner.Scan() {
 			line := lineScanner.Text()
 			logger.Printf("[%s] %s", p, line)
 		}
+		// keep draining if a line is too long, otherwise the writer would block
+		_, _ = io.Copy(io.Discard, reader)
 	}()
-	return writer
+	return &lineLogWriter{PipeWriter: writer, done: done}
 }
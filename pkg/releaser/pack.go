@@ -0,0 +1,27 @@
+package releaser
+
+import (
+	"os"
+	"path"
+
+	"github.com/christophwitzko/npm-binary-releaser/pkg/config"
+	"github.com/christophwitzko/npm-binary-releaser/pkg/pack"
+)
+
+func packPackages(c *config.Config, logger Logger, plan *Plan) error {
+	for _, pkg := range plan.Packages {
+		packageName := pkg.Name
+		tarball, err := pack.Pack(pkg.Dir)
+		if err != nil {
+			return err
+		}
+		tarballPath := path.Join(c.OutputDirPath, pack.FileName(packageName, c.PackageVersion))
+		logger.Printf("[%s] creating tarball %s", packageName, tarballPath)
+		if err := os.WriteFile(tarballPath, tarball.Data, 0644); err != nil {
+			return err
+		}
+		logger.Printf("[%s] shasum: %s", packageName, tarball.Shasum)
+		logger.Printf("[%s] integrity: %s", packageName, tarball.Integrity)
+	}
+	return nil
+}
@@ -0,0 +1,316 @@
+package releaser
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/christophwitzko/npm-binary-releaser/pkg/config"
+	"github.com/christophwitzko/npm-binary-releaser/pkg/helper"
+)
+
+// PackagePlan describes a package that will be generated and published.
+type PackagePlan struct {
+	Name         string   `json:"name"`
+	Version      string   `json:"version"`
+	OS           string   `json:"os,omitempty"`
+	CPU          string   `json:"cpu,omitempty"`
+	Libc         string   `json:"libc,omitempty"`
+	Variant      string   `json:"variant,omitempty"`
+	Source       string   `json:"source,omitempty"`
+	BinFileName  string   `json:"binFileName"`
+	ExtraFiles   []string `json:"extraFiles,omitempty"`
+	Dir          string   `json:"dir"`
+	DistTag      string   `json:"distTag"`
+	PublishOrder int      `json:"publishOrder"`
+	// Binaries lists all binaries of a platform package if multiple binaries are released.
+	Binaries []*PackageBinary `json:"binaries,omitempty"`
+
+	binFile *helper.BinFile
+}
+
+// PackageBinary is a binary that is included in a platform package.
+type PackageBinary struct {
+	Name        string   `json:"name"`
+	Source      string   `json:"source"`
+	BinFileName string   `json:"binFileName"`
+	ExtraFiles  []string `json:"extraFiles,omitempty"`
+
+	binFile *helper.BinFile
+}
+
+// packageBinaries returns all binaries of the package, even if only a single binary is released.
+func (p *PackagePlan) packageBinaries() []*PackageBinary {
+	if len(p.Binaries) > 0 {
+		return p.Binaries
+	}
+	return []*PackageBinary{{
+		Name:        p.binFile.BinName,
+		Source:      p.Source,
+		BinFileName: p.BinFileName,
+		ExtraFiles:  p.ExtraFiles,
+		binFile:     p.binFile,
+	}}
+}
+
+// Plan contains all packages in publish order. The main package is always the last one.
+type Plan struct {
+	Packages []*PackagePlan `json:"packages"`
+}
+
+// PlatformPackages returns all packages except the main package.
+func (p *Plan) PlatformPackages() []*PackagePlan {
+	return p.Packages[:len(p.Packages)-1]
+}
+
+// MainPackage returns the main package that depends on all platform packages.
+func (p *Plan) MainPackage() *PackagePlan {
+	return p.Packages[len(p.Packages)-1]
+}
+
+func newPlan(c *config.Config, logger Logger, workDir string) (*Plan, error) {
+	foundFiles, err := findBinaryFiles(c, logger, workDir)
+	if err != nil {
+		return nil, err
+	}
+	foundFiles = filterPlatforms(c, logger, foundFiles)
+	if len(foundFiles) == 0 {
+		return nil, fmt.Errorf("all binaries were excluded by the platform filters")
+	}
+	if err := checkRequiredPlatforms(c, foundFiles); err != nil {
+		return nil, err
+	}
+	foundFiles, err = resolveDuplicates(c, logger, foundFiles)
+	if err != nil {
+		return nil, err
+	}
+	// the libc of explicitly mapped platforms is kept
+	if len(c.Platforms) == 0 {
+		resolveLibcVariants(logger, foundFiles)
+	}
+
+	targets, filesPerTarget, err := groupBinaryFiles(c, foundFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{Packages: make([]*PackagePlan, 0, len(targets)+1)}
+	for _, target := range targets {
+		files := filesPerTarget[target]
+		file := files[0]
+		packageName := fmt.Sprintf("%s-%s", c.PackageName, target)
+		pkg := &PackagePlan{
+			Name:         fmt.Sprintf("%s%s", c.PackageNamePrefix, packageName),
+			Version:      c.PackageVersion,
+			OS:           file.Platform,
+			CPU:          file.Arch,
+			Libc:         file.Libc,
+			Variant:      file.Variant,
+			Source:       file.Source,
+			BinFileName:  binaryFileName(file.Platform, packageName),
+			ExtraFiles:   file.ExtraFiles,
+			Dir:          path.Join(c.OutputDirPath, packageName),
+			DistTag:      c.DistTag,
+			PublishOrder: len(plan.Packages) + 1,
+			binFile:      file,
+		}
+		if c.HasMultipleBinaries() {
+			pkg.ExtraFiles = nil
+			for _, binFile := range files {
+				pkg.Binaries = append(pkg.Binaries, &PackageBinary{
+					Name:        binFile.BinName,
+					Source:      binFile.Source,
+					BinFileName: binaryFileName(binFile.Platform, binFile.BinName),
+					ExtraFiles:  binFile.ExtraFiles,
+					binFile:     binFile,
+				})
+				for _, extraFile := range binFile.ExtraFiles {
+					if !slices.Contains(pkg.ExtraFiles, extraFile) {
+						pkg.ExtraFiles = append(pkg.ExtraFiles, extraFile)
+					}
+				}
+			}
+			pkg.BinFileName = pkg.Binaries[0].BinFileName
+		}
+		plan.Packages = append(plan.Packages, pkg)
+	}
+
+	plan.Packages = append(plan.Packages, &PackagePlan{
+		Name:         c.MainPackageName(),
+		Version:      c.PackageVersion,
+		BinFileName:  "run.js",
+		Dir:          path.Join(c.OutputDirPath, c.PackageName),
+		DistTag:      c.DistTag,
+		PublishOrder: len(plan.Packages) + 1,
+	})
+	return plan, nil
+}
+
+// platformMatches returns true if one of the glob patterns matches the platform-arch (e.g. linux-x64) or the
+// target including the libc (e.g. linux-x64-musl) of the file.
+func platformMatches(patterns []string, file *helper.BinFile) bool {
+	for _, pattern := range patterns {
+		for _, name := range []string{file.Platform + "-" + file.Arch, file.Target()} {
+			if ok, _ := path.Match(pattern, name); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// filterPlatforms removes all binaries that are not included or that are excluded by the config.
+func filterPlatforms(c *config.Config, logger Logger, files []*helper.BinFile) []*helper.BinFile {
+	if len(c.IncludePlatforms) == 0 && len(c.ExcludePlatforms) == 0 {
+		return files
+	}
+	filtered := make([]*helper.BinFile, 0, len(files))
+	for _, file := range files {
+		if len(c.IncludePlatforms) > 0 && !platformMatches(c.IncludePlatforms, file) {
+			logger.Printf("skipping %s (%s), it is not included", file.FileName, file.Target())
+			continue
+		}
+		if platformMatches(c.ExcludePlatforms, file) {
+			logger.Printf("skipping %s (%s), it is excluded", file.FileName, file.Target())
+			continue
+		}
+		filtered = append(filtered, file)
+	}
+	return filtered
+}
+
+// checkRequiredPlatforms returns an error that lists all required platforms without binary.
+func checkRequiredPlatforms(c *config.Config, files []*helper.BinFile) error {
+	missing := make([]string, 0)
+	for _, required := range c.RequiredPlatforms {
+		found := slices.ContainsFunc(files, func(file *helper.BinFile) bool {
+			return required == file.Platform+"-"+file.Arch || required == file.Target()
+		})
+		if !found {
+			missing = append(missing, required)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("required platforms are missing: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// resolveDuplicates ensures that there is only one file per binary and target. Conflicts are resolved by the
+// first prefer sources pattern that matches exactly one of the conflicting files. All unresolved conflicts are
+// reported at once, before anything is written.
+func resolveDuplicates(c *config.Config, logger Logger, files []*helper.BinFile) ([]*helper.BinFile, error) {
+	keys := make([]string, 0, len(files))
+	filesPerKey := make(map[string][]*helper.BinFile)
+	for _, file := range files {
+		key := file.BinName + "/" + file.Target()
+		if _, ok := filesPerKey[key]; !ok {
+			keys = append(keys, key)
+		}
+		filesPerKey[key] = append(filesPerKey[key], file)
+	}
+
+	resolved := make([]*helper.BinFile, 0, len(keys))
+	errs := make([]error, 0)
+	for _, key := range keys {
+		keyFiles := filesPerKey[key]
+		if len(keyFiles) == 1 {
+			resolved = append(resolved, keyFiles[0])
+			continue
+		}
+		sources := make([]string, 0, len(keyFiles))
+		for _, file := range keyFiles {
+			sources = append(sources, file.Source)
+		}
+		file, pattern := preferredFile(c.PreferSources, keyFiles)
+		if file == nil {
+			errs = append(errs, fmt.Errorf("multiple %s binaries for %s: %s", keyFiles[0].BinName, keyFiles[0].Target(), strings.Join(sources, ", ")))
+			continue
+		}
+		logger.Printf("found multiple %s binaries for %s, using %s (preferred by %s)", file.BinName, file.Target(), file.Source, pattern)
+		resolved = append(resolved, file)
+	}
+	if len(errs) > 0 {
+		errs = append(errs, fmt.Errorf("remove the duplicates or configure preferSources to choose one"))
+		return nil, errors.Join(errs...)
+	}
+	return resolved, nil
+}
+
+// preferredFile returns the file that is the only one whose source name matches a pattern, trying the
+// patterns in order.
+func preferredFile(patterns []string, files []*helper.BinFile) (*helper.BinFile, string) {
+	for _, pattern := range patterns {
+		var match *helper.BinFile
+		matches := 0
+		for _, file := range files {
+			if ok, _ := path.Match(pattern, filepath.Base(file.Source)); ok {
+				match = file
+				matches++
+			}
+		}
+		if matches == 1 {
+			return match, pattern
+		}
+	}
+	return nil, ""
+}
+
+// groupBinaryFiles groups the binary files by their target in the order they were found. Every target must
+// contain all configured binaries, ordered like the config. Duplicates must be resolved before.
+func groupBinaryFiles(c *config.Config, files []*helper.BinFile) ([]string, map[string][]*helper.BinFile, error) {
+	targets := make([]string, 0, len(files))
+	filesPerTarget := make(map[string][]*helper.BinFile)
+	for _, file := range files {
+		target := file.Target()
+		if _, ok := filesPerTarget[target]; !ok {
+			targets = append(targets, target)
+		}
+		filesPerTarget[target] = append(filesPerTarget[target], file)
+	}
+
+	errs := make([]error, 0)
+	for _, target := range targets {
+		targetFiles := make([]*helper.BinFile, 0, len(c.Binaries))
+		for _, binary := range c.Binaries {
+			i := slices.IndexFunc(filesPerTarget[target], func(file *helper.BinFile) bool {
+				return file.BinName == binary.Name
+			})
+			if i < 0 {
+				errs = append(errs, fmt.Errorf("binary %s is missing for %s", binary.Name, target))
+				continue
+			}
+			targetFiles = append(targetFiles, filesPerTarget[target][i])
+		}
+		filesPerTarget[target] = targetFiles
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, nil, err
+	}
+	return targets, filesPerTarget, nil
+}
+
+// binaryFileName returns the file name of a binary in a platform package.
+func binaryFileName(platform, name string) string {
+	if platform == "win32" {
+		return name + ".exe"
+	}
+	return name
+}
+
+// CreatePlan validates the config and discovers all binaries without writing or publishing any package.
+func CreatePlan(c *config.Config, logger Logger) (*Plan, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	workDir, err := os.MkdirTemp("", "npm-binary-releaser-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(workDir)
+	return newPlan(c, logger, workDir)
+}
@@ -0,0 +1,252 @@
+package releaser
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/christophwitzko/npm-binary-releaser/pkg/config"
+	"github.com/christophwitzko/npm-binary-releaser/pkg/pack"
+	"github.com/christophwitzko/npm-binary-releaser/pkg/registry"
+)
+
+func readPackageManifest(pkgDir string) (map[string]any, error) {
+	data, err := os.ReadFile(path.Join(pkgDir, "package.json"))
+	if err != nil {
+		return nil, err
+	}
+	manifest := make(map[string]any)
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// publishFunc publishes a single package. All log output must be written to the given (package prefixed) logger.
+type publishFunc func(logger Logger, pkg *PackagePlan) error
+
+// publishAll publishes all platform packages concurrently with at most c.PublishConcurrency workers.
+// The main package is only published if all platform packages succeeded.
+func publishAll(c *config.Config, logger Logger, plan *Plan, publishPackage publishFunc) error {
+	platformPackages := plan.PlatformPackages()
+	logger.Printf("publishing %d platform packages (concurrency: %d)", len(platformPackages), c.PublishConcurrency)
+
+	sem := make(chan struct{}, c.PublishConcurrency)
+	errs := make([]error, len(platformPackages))
+	wg := sync.WaitGroup{}
+	for i, pkg := range platformPackages {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			errs[i] = publishWithRetry(c, newPrefixedLogger(logger, pkg.Name), pkg, publishPackage)
+		}()
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("publishing platform packages failed, the main package was not published: %w", err)
+	}
+
+	mainPackage := plan.MainPackage()
+	return publishWithRetry(c, newPrefixedLogger(logger, mainPackage.Name), mainPackage, publishPackage)
+}
+
+// publishWithRetry retries retryable registry errors with exponential backoff.
+func publishWithRetry(c *config.Config, logger Logger, pkg *PackagePlan, publishPackage publishFunc) error {
+	delay := c.PublishRetryDelay
+	for attempt := 1; ; attempt++ {
+		err := publishPackage(logger, pkg)
+		if err == nil || !registry.IsRetryable(err) || attempt > c.PublishRetries {
+			return err
+		}
+		logger.Printf("attempt %d failed: %v", attempt, err)
+		logger.Printf("retrying in %s", delay)
+		time.Sleep(delay)
+		delay = min(delay*2, max(c.PublishRetryMaxDelay, c.PublishRetryDelay))
+	}
+}
+
+func newRegistryClient(c *config.Config) (*registry.Client, error) {
+	token := os.Getenv("NPM_TOKEN")
+	if token == "" {
+		return nil, fmt.Errorf("NPM_TOKEN is not set")
+	}
+	return registry.NewClient(c.PublishRegistry, token), nil
+}
+
+func publish(c *config.Config, logger Logger, plan *Plan) error {
+	client, err := newRegistryClient(c)
+	if err != nil {
+		return err
+	}
+	return publishAll(c, logger, plan, func(logger Logger, pkg *PackagePlan) error {
+		manifest, err := readPackageManifest(pkg.Dir)
+		if err != nil {
+			return err
+		}
+		tarball, err := pack.Pack(pkg.Dir)
+		if err != nil {
+			return err
+		}
+		published, err := isPublished(client, pkg.Name, pkg.Version, tarball)
+		if err != nil {
+			return err
+		}
+		if published {
+			logger.Printf("version %s is already published, skipping", pkg.Version)
+			return nil
+		}
+		logger.Printf("publishing version %s to %s (tag: %s)", pkg.Version, c.PublishRegistry, pkg.DistTag)
+		return client.Publish(context.Background(), manifest, tarball, pkg.DistTag)
+	})
+}
+
+// isPublished reports whether the version is already published with the same tarball.
+// It returns an error if the version was published with a different tarball.
+func isPublished(client *registry.Client, name, version string, tarball *pack.Tarball) (bool, error) {
+	packument, err := client.GetPackument(context.Background(), name)
+	if errors.Is(err, registry.ErrPackageNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	publishedVersion, ok := packument.Versions[version]
+	if !ok {
+		return false, nil
+	}
+	if publishedVersion.Dist.Integrity != tarball.Integrity {
+		return false, &registry.Error{
+			Op:      "publish",
+			Package: name,
+			Version: version,
+			Kind:    registry.ErrorKindVersionExists,
+			Message: fmt.Sprintf("already published with a different integrity (%s)", publishedVersion.Dist.Integrity),
+		}
+	}
+	return true, nil
+}
+
+func publishWithNpm(c *config.Config, logger Logger, plan *Plan) error {
+	if os.Getenv("NPM_CONFIG_USERCONFIG") == "" {
+		if _, err := os.Stat(".npmrc"); os.IsNotExist(err) {
+			registryName := strings.TrimPrefix(c.PublishRegistry, "https://")
+			logger.Printf("creating .npmrc for %s", registryName)
+			npmRcData := fmt.Sprintf("//%s:_authToken=${NPM_TOKEN}\n", registryName)
+			if err := os.WriteFile(".npmrc", []byte(npmRcData), 0644); err != nil {
+				return err
+			}
+		}
+	}
+
+	// npm publishes the deterministic tarball, hence its integrity matches the one embedded for install.js
+	tarballDir, err := os.MkdirTemp("", "npm-binary-releaser-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tarballDir)
+
+	// the registry client is only used to check which versions are already published
+	client := registry.NewClient(c.PublishRegistry, os.Getenv("NPM_TOKEN"))
+	return publishAll(c, logger, plan, func(logger Logger, pkg *PackagePlan) error {
+		tarball, err := pack.Pack(pkg.Dir)
+		if err != nil {
+			return err
+		}
+		published, err := isPublished(client, pkg.Name, pkg.Version, tarball)
+		var registryErr *registry.Error
+		if errors.As(err, &registryErr) && registryErr.Kind == registry.ErrorKindAuth {
+			// the registry might require the auth of the npm config for reads, npm publish decides
+			logger.Printf("could not check if version %s is already published: %v", pkg.Version, err)
+		} else if err != nil {
+			return err
+		}
+		if published {
+			logger.Printf("version %s is already published, skipping", pkg.Version)
+			return nil
+		}
+		tarballPath := filepath.Join(tarballDir, pack.FileName(pkg.Name, pkg.Version))
+		if err := os.WriteFile(tarballPath, tarball.Data, 0644); err != nil {
+			return err
+		}
+		logger.Printf("running npm publish %s (tag: %s)", tarballPath, pkg.DistTag)
+		output := &lockedBuffer{}
+		stdout, stderr := prefixedWriter(logger, "npm"), prefixedWriter(logger, "npm")
+		cmd := exec.Command("npm", "publish", "--tag", pkg.DistTag, tarballPath)
+		cmd.Stdout = stdout
+		cmd.Stderr = io.MultiWriter(stderr, output)
+		err = cmd.Run()
+		_ = stdout.Close()
+		_ = stderr.Close()
+		if err != nil {
+			kind := classifyNpmOutput(output.String())
+			// the version might have been published concurrently (e.g. by a previous attempt that timed out)
+			if kind == registry.ErrorKindVersionExists {
+				if published, _ := isPublished(client, pkg.Name, pkg.Version, tarball); published {
+					logger.Printf("version %s is already published with the same tarball", pkg.Version)
+					return nil
+				}
+			}
+			return &registry.Error{
+				Op:      "publish",
+				Package: pkg.Name,
+				Version: pkg.Version,
+				Kind:    kind,
+				Err:     err,
+			}
+		}
+		return nil
+	})
+}
+
+var npmErrorCodeRegexp = regexp.MustCompile(`\bE(\d{3}|[A-Z]+)\b`)
+
+// classifyNpmOutput classifies a failed npm publish by the error codes npm prints (e.g. npm ERR! code E503).
+func classifyNpmOutput(output string) registry.ErrorKind {
+	if strings.Contains(output, "EPUBLISHCONFLICT") || strings.Contains(output, "previously published") {
+		return registry.ErrorKindVersionExists
+	}
+	for _, match := range npmErrorCodeRegexp.FindAllStringSubmatch(output, -1) {
+		code := match[1]
+		if statusCode, err := strconv.Atoi(code); err == nil {
+			return registry.KindFromStatusCode(statusCode)
+		}
+		switch code {
+		case "TIMEDOUT", "CONNRESET", "CONNREFUSED", "AI_AGAIN", "SOCKETTIMEOUT", "NOTFOUND":
+			return registry.ErrorKindNetwork
+		case "NEEDAUTH", "OTP":
+			return registry.ErrorKindAuth
+		}
+	}
+	return registry.ErrorKindUnknown
+}
+
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
@@ -2,15 +2,13 @@ package releaser
 
 import (
 	"encoding/json"
-	"fmt"
 	"os"
-	"os/exec"
 	"path"
 	"path/filepath"
-	"strings"
 
 	"github.com/christophwitzko/npm-binary-releaser/pkg/config"
 	"github.com/christophwitzko/npm-binary-releaser/pkg/helper"
+	"github.com/christophwitzko/npm-binary-releaser/pkg/pack"
 	"github.com/christophwitzko/npm-binary-releaser/pkg/templates"
 )
 
@@ -18,11 +16,7 @@ func Run(c *config.Config, logger Logger) error {
 	if err := c.Validate(); err != nil {
 		return err
 	}
-	logger.Printf("creating release %s for %s (%s)", c.PackageVersion, c.PackageName, c.BinName)
-	logger.Printf("creating output directory: %s", c.OutputDirPath)
-	if err := helper.EnsureOutputDirectory(c.OutputDirPath); err != nil {
-		return err
-	}
+	logger.Printf("creating release %s (tag: %s) for %s (%s)", c.PackageVersion, c.DistTag, c.PackageName, c.BinName)
 
 	const readmeFileName = "README.md"
 	includeReadme := false
@@ -42,81 +36,85 @@ func Run(c *config.Config, logger Logger) error {
 		return helper.CopyFile(c.ReadmePath, path.Join(pkgDir, readmeFileName))
 	}
 
-	logger.Printf("reading binary files from: %s", c.InputBinDirPath)
-	files, err := os.ReadDir(c.InputBinDirPath)
+	workDir, err := os.MkdirTemp("", "npm-binary-releaser-")
 	if err != nil {
 		return err
 	}
+	defer os.RemoveAll(workDir)
 
-	foundFiles := make([]*helper.BinFile, 0, len(files))
-	for _, file := range files {
-		logger.Printf("checking file %s", file.Name())
-		platform, arch := helper.ExtractOsAndArchFromFileName(file.Name())
-		if platform == "" || arch == "" {
-			logger.Printf("no os/arch found for %s", file.Name())
-			continue
-		}
-		fPath := path.Join(c.InputBinDirPath, file.Name())
-		if file.IsDir() {
-			execPath, err := helper.FindFirstExecutableFileInDir(fPath)
-			if err != nil {
-				logger.Printf("could not find bin file in dir %s %v", fPath, err)
-				continue
-			}
-			fPath = execPath
-		}
-		foundFiles = append(foundFiles, &helper.BinFile{
-			Platform: platform,
-			Arch:     arch,
-			Path:     fPath,
-			FileName: file.Name(),
-		})
+	plan, err := newPlan(c, logger, workDir)
+	if err != nil {
+		return err
 	}
 
-	if len(foundFiles) == 0 {
-		return fmt.Errorf("no binary files found at %s", c.InputBinDirPath)
+	logger.Printf("creating output directory: %s", c.OutputDirPath)
+	if err := helper.EnsureOutputDirectory(c.OutputDirPath); err != nil {
+		return err
 	}
 
-	allPackageDirs := make([]string, 0, len(files)+1)
 	optionalDependencies := make(map[string]string)
-	for _, file := range foundFiles {
-		packageName := fmt.Sprintf("%s-%s-%s", c.PackageName, file.Platform, file.Arch)
-		fullPackageName := fmt.Sprintf("%s%s", c.PackageNamePrefix, packageName)
-		pkgDir := path.Join(c.OutputDirPath, packageName)
-
-		logger.Printf("[%s] creating package at %s", fullPackageName, pkgDir)
-		if err := os.Mkdir(pkgDir, 0755); err != nil {
+	supportedPlatforms := make([]string, 0, len(plan.Packages))
+	platformIntegrity := make(map[string]string)
+	for _, pkg := range plan.PlatformPackages() {
+		file := pkg.binFile
+		logger.Printf("[%s] creating package at %s", pkg.Name, pkg.Dir)
+		if err := os.Mkdir(pkg.Dir, 0755); err != nil {
 			return err
 		}
 
-		binFileName := packageName
-		if file.Platform == "win32" {
-			binFileName += ".exe"
+		binaries := pkg.packageBinaries()
+		binFileNames := make([]string, 0, len(binaries))
+		for _, binary := range binaries {
+			binFileNames = append(binFileNames, binary.BinFileName)
 		}
-
-		logger.Printf("[%s] creating package.json", fullPackageName)
-		pjsTemplate := templates.NewBinPackageJson(c, fullPackageName, file.Platform, file.Arch, binFileName)
+		logger.Printf("[%s] creating package.json", pkg.Name)
+		pjsTemplate := templates.NewBinPackageJson(c, pkg.Name, file.Platform, file.Arch, file.Libc, binFileNames...)
+		pjsTemplate.Files = append(pjsTemplate.Files, pkg.ExtraFiles...)
 		pjsData, err := json.MarshalIndent(pjsTemplate, "", "  ")
 		if err != nil {
 			return err
 		}
-		if err := os.WriteFile(path.Join(pkgDir, "package.json"), pjsData, 0644); err != nil {
+		if err := os.WriteFile(path.Join(pkg.Dir, "package.json"), pjsData, 0644); err != nil {
 			return err
 		}
 
-		logger.Printf("[%s] copying binary file to %s", fullPackageName, binFileName)
-		if err := helper.CopyFile(file.Path, path.Join(pkgDir, binFileName)); err != nil {
-			return err
+		copiedExtraFiles := make(map[string]bool)
+		for _, binary := range binaries {
+			logger.Printf("[%s] copying binary file to %s", pkg.Name, binary.BinFileName)
+			if err := helper.CopyFile(binary.binFile.Path, path.Join(pkg.Dir, binary.BinFileName)); err != nil {
+				return err
+			}
+			for _, extraFile := range binary.ExtraFiles {
+				if copiedExtraFiles[extraFile] {
+					continue
+				}
+				copiedExtraFiles[extraFile] = true
+				logger.Printf("[%s] copying %s", pkg.Name, extraFile)
+				extraFilePath := path.Join(pkg.Dir, extraFile)
+				if err := os.MkdirAll(path.Dir(extraFilePath), 0755); err != nil {
+					return err
+				}
+				if err := helper.CopyFile(filepath.Join(filepath.Dir(binary.binFile.Path), filepath.FromSlash(extraFile)), extraFilePath); err != nil {
+					return err
+				}
+			}
 		}
-		optionalDependencies[fullPackageName] = c.PackageVersion
-		allPackageDirs = append(allPackageDirs, pkgDir)
-	}
+		optionalDependencies[pkg.Name] = c.PackageVersion
+		supportedPlatforms = append(supportedPlatforms, file.Target())
 
-	mainPackageDir := path.Join(c.OutputDirPath, c.PackageName)
-	mainPackageName := fmt.Sprintf("%s%s", c.PackageNamePrefix, c.PackageName)
-	if c.NoPrefixForMainPackage && c.PackageNamePrefix != "" {
-		mainPackageName = c.PackageName
+		if c.InstallFallback {
+			// the tarball is deterministic, install.js verifies the downloaded package against this integrity
+			tarball, err := pack.Pack(pkg.Dir)
+			if err != nil {
+				return err
+			}
+			platformIntegrity[pkg.Name] = tarball.Integrity
+		}
 	}
+
+	mainPackage := plan.MainPackage()
+	mainPackageDir := mainPackage.Dir
+	mainPackageName := mainPackage.Name
 	logger.Printf("[%s] creating main package at %s", mainPackageName, mainPackageDir)
 
 	// create package folder
@@ -129,6 +127,10 @@ func Run(c *config.Config, logger Logger) error {
 	if c.NoPrefixForMainPackage && c.PackageNamePrefix != "" {
 		pjsTemplate.BinPkgPrefix = c.PackageNamePrefix
 	}
+	pjsTemplate.SupportedPlatforms = supportedPlatforms
+	if c.InstallFallback {
+		pjsTemplate.PlatformIntegrity = platformIntegrity
+	}
 	pjsData, err := json.MarshalIndent(pjsTemplate, "", "  ")
 	if err != nil {
 		return err
@@ -137,46 +139,61 @@ func Run(c *config.Config, logger Logger) error {
 		return err
 	}
 
-	// create run.js
-	logger.Printf("[%s] creating run.js", mainPackageName)
-	if err := os.WriteFile(path.Join(mainPackageDir, "run.js"), templates.RunJs, 0755); err != nil {
+	// create a launcher for every binary
+	for _, launcher := range templates.LauncherFileNames(c) {
+		logger.Printf("[%s] creating %s", mainPackageName, launcher)
+		if err := os.WriteFile(path.Join(mainPackageDir, launcher), templates.RunJs, 0755); err != nil {
+			return err
+		}
+	}
+
+	// create JS API
+	logger.Printf("[%s] creating index.js, index.d.ts and platform.js", mainPackageName)
+	if err := os.WriteFile(path.Join(mainPackageDir, "index.js"), templates.IndexJs, 0644); err != nil {
 		return err
 	}
-	if err := copyReadme(mainPackageName, mainPackageDir); err != nil {
+	if err := os.WriteFile(path.Join(mainPackageDir, "index.d.ts"), templates.IndexDTs, 0644); err != nil {
 		return err
 	}
-
-	if !c.Publish {
-		logger.Printf("skipping npm publish step")
-		return nil
+	if err := os.WriteFile(path.Join(mainPackageDir, "platform.js"), templates.PlatformJs, 0644); err != nil {
+		return err
 	}
 
-	if os.Getenv("NPM_CONFIG_USERCONFIG") == "" {
-		if _, err = os.Stat(".npmrc"); os.IsNotExist(err) {
-			registryName := strings.TrimPrefix(c.PublishRegistry, "https://")
-			logger.Printf("creating .npmrc for %s", registryName)
-			npmRcData := fmt.Sprintf("//%s:_authToken=${NPM_TOKEN}\n", registryName)
-			if err := os.WriteFile(".npmrc", []byte(npmRcData), 0644); err != nil {
-				return err
-			}
+	if c.InstallFallback {
+		logger.Printf("[%s] creating install.js", mainPackageName)
+		if err := os.WriteFile(path.Join(mainPackageDir, "install.js"), templates.InstallJs, 0755); err != nil {
+			return err
 		}
 	}
-
-	allPackageDirs = append(allPackageDirs, mainPackageDir)
-	for _, pDir := range allPackageDirs {
-		publishDir, err := filepath.Abs(pDir)
-		if err != nil {
+	if c.DirectBinLink {
+		logger.Printf("[%s] creating link.js", mainPackageName)
+		if err := os.WriteFile(path.Join(mainPackageDir, "link.js"), templates.LinkJs, 0755); err != nil {
 			return err
 		}
-		logger.Printf("running npm publish in %s", publishDir)
-		cmd := exec.Command("npm", "publish", publishDir)
-		cmd.Stdout = prefixedWriter(logger, "publish")
-		cmd.Stderr = prefixedWriter(logger, "publish")
-		if err := cmd.Run(); err != nil {
+	}
+	if err := copyReadme(mainPackageName, mainPackageDir); err != nil {
+		return err
+	}
+
+	if c.Pack {
+		if err := packPackages(c, logger, plan); err != nil {
 			return err
 		}
 	}
 
+	if !c.Publish {
+		logger.Printf("skipping npm publish step")
+		return nil
+	}
+
+	publishPackages := publish
+	if c.PublishWithNpm {
+		publishPackages = publishWithNpm
+	}
+	if err := publishPackages(c, logger, plan); err != nil {
+		return err
+	}
+
 	logger.Println("done.")
 	return nil
 }
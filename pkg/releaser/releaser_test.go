@@ -0,0 +1,1367 @@
+package releaser
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"debug/elf"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/christophwitzko/npm-binary-releaser/pkg/config"
+	"github.com/christophwitzko/npm-binary-releaser/pkg/helper"
+	"github.com/christophwitzko/npm-binary-releaser/pkg/registry"
+	"github.com/christophwitzko/npm-binary-releaser/pkg/templates"
+)
+
+var testLogger = log.New(io.Discard, "", 0)
+
+func newTestConfig(t *testing.T, binFiles ...string) *config.Config {
+	t.Helper()
+	inputDir := t.TempDir()
+	for _, binFile := range binFiles {
+		if err := os.WriteFile(filepath.Join(inputDir, binFile), []byte("#!/bin/sh\necho "+binFile+"\n"), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &config.Config{
+		BinName:         "my-cli",
+		InputBinDirPath: inputDir,
+		PackageVersion:  "1.0.0",
+		OutputDirPath:   filepath.Join(t.TempDir(), "generated-packages"),
+		PublishRegistry: config.DefaultPublishRegistry,
+	}
+}
+
+func TestCreatePlan(t *testing.T) {
+	c := newTestConfig(t, "my-cli_darwin_arm64", "my-cli_linux_amd64", "my-cli_windows_amd64.exe", "README.md")
+	c.PackageNamePrefix = "@my-org/"
+	plan, err := CreatePlan(c, testLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantNames := []string{"@my-org/my-cli-darwin-arm64", "@my-org/my-cli-linux-x64", "@my-org/my-cli-win32-x64", "@my-org/my-cli"}
+	if len(plan.Packages) != len(wantNames) {
+		t.Fatalf("got %d packages, want %d", len(plan.Packages), len(wantNames))
+	}
+	for i, pkg := range plan.Packages {
+		if pkg.Name != wantNames[i] || pkg.PublishOrder != i+1 {
+			t.Fatalf("package %d = %s (order %d), want %s", i, pkg.Name, pkg.PublishOrder, wantNames[i])
+		}
+	}
+	if got := plan.PlatformPackages()[2].BinFileName; got != "my-cli-win32-x64.exe" {
+		t.Fatalf("bin file name = %q, want %q", got, "my-cli-win32-x64.exe")
+	}
+	if plan.MainPackage().BinFileName != "run.js" {
+		t.Fatalf("unexpected main package %+v", plan.MainPackage())
+	}
+	if _, err := os.Stat(c.OutputDirPath); !os.IsNotExist(err) {
+		t.Fatalf("plan must not create the output directory: %v", err)
+	}
+}
+
+// testRegistry is an in-memory stand-in for the npm registry.
+type testRegistry struct {
+	*httptest.Server
+	mu         sync.Mutex
+	packuments map[string]*registry.Packument
+	tarballs   map[string][]byte
+	publishes  []string
+	// publishStatus optionally overrides the response status of a publish request
+	publishStatus func(name string) int
+	// readToken is required for reading packuments and tarballs if set
+	readToken string
+}
+
+func newTestRegistry(t *testing.T) *testRegistry {
+	t.Helper()
+	r := &testRegistry{packuments: make(map[string]*registry.Packument), tarballs: make(map[string][]byte)}
+	r.Server = httptest.NewServer(http.HandlerFunc(r.handle))
+	t.Cleanup(r.Close)
+	t.Setenv("NPM_TOKEN", "test-token")
+	return r
+}
+
+func (r *testRegistry) handle(w http.ResponseWriter, req *http.Request) {
+	reqPath := strings.TrimPrefix(req.URL.EscapedPath(), "/")
+	if strings.HasPrefix(reqPath, "-/package/") {
+		r.handleDistTag(w, req, strings.Split(strings.TrimPrefix(reqPath, "-/package/"), "/"))
+		return
+	}
+	name, err := url.PathUnescape(reqPath)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if req.Method == http.MethodGet && r.readToken != "" && req.Header.Get("Authorization") != "Bearer "+r.readToken {
+		http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+	if strings.Contains(name, "/-/") {
+		tarball, ok := r.tarballs[name]
+		if !ok {
+			http.NotFound(w, req)
+			return
+		}
+		_, _ = w.Write(tarball)
+		return
+	}
+	switch req.Method {
+	case http.MethodGet:
+		packument, ok := r.packuments[name]
+		if !ok {
+			http.Error(w, `{"error":"not found"}`, http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(packument)
+	case http.MethodPut:
+		if r.publishStatus != nil {
+			if status := r.publishStatus(name); status != 0 {
+				http.Error(w, `{"error":"failed"}`, status)
+				return
+			}
+		}
+		doc := &registry.PublishDocument{}
+		if err := json.NewDecoder(req.Body).Decode(doc); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		packument, ok := r.packuments[name]
+		if !ok {
+			packument = &registry.Packument{Name: name, DistTags: map[string]string{}, Versions: map[string]registry.PackumentVersion{}}
+			r.packuments[name] = packument
+		}
+		for version, manifest := range doc.Versions {
+			if _, exists := packument.Versions[version]; exists {
+				http.Error(w, `{"error":"cannot publish over previously published version"}`, http.StatusForbidden)
+				return
+			}
+			data, _ := json.Marshal(manifest)
+			publishedVersion := registry.PackumentVersion{}
+			_ = json.Unmarshal(data, &publishedVersion)
+			packument.Versions[version] = publishedVersion
+			tarballURL, _ := url.Parse(publishedVersion.Dist.Tarball)
+			for _, attachment := range doc.Attachments {
+				r.tarballs[strings.TrimPrefix(tarballURL.Path, "/")], _ = base64.StdEncoding.DecodeString(attachment.Data)
+			}
+		}
+		for tag, version := range doc.DistTags {
+			packument.DistTags[tag] = version
+		}
+		r.publishes = append(r.publishes, name)
+		w.WriteHeader(http.StatusCreated)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// handleDistTag handles requests to /-/package/<name>/dist-tags/<tag>.
+func (r *testRegistry) handleDistTag(w http.ResponseWriter, req *http.Request, parts []string) {
+	if len(parts) != 3 || parts[1] != "dist-tags" {
+		http.NotFound(w, req)
+		return
+	}
+	name, _ := url.PathUnescape(parts[0])
+	tag, _ := url.PathUnescape(parts[2])
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	packument, ok := r.packuments[name]
+	if !ok {
+		http.NotFound(w, req)
+		return
+	}
+	switch req.Method {
+	case http.MethodPut:
+		var version string
+		if err := json.NewDecoder(req.Body).Decode(&version); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		packument.DistTags[tag] = version
+	case http.MethodDelete:
+		delete(packument.DistTags, tag)
+	}
+	w.WriteHeader(http.StatusCreated)
+}
+
+func TestRunSkipsPublishedVersions(t *testing.T) {
+	reg := newTestRegistry(t)
+	c := newTestConfig(t, "my-cli_darwin_arm64", "my-cli_linux_amd64")
+	c.Publish = true
+	c.PublishRegistry = reg.URL
+	if err := Run(c, testLogger); err != nil {
+		t.Fatal(err)
+	}
+	if len(reg.publishes) != 3 || reg.publishes[2] != "my-cli" {
+		t.Fatalf("unexpected publishes %v", reg.publishes)
+	}
+
+	// simulate a partially failed release
+	delete(reg.packuments, "my-cli")
+	reg.publishes = nil
+	if err := Run(c, testLogger); err != nil {
+		t.Fatal(err)
+	}
+	if len(reg.publishes) != 1 || reg.publishes[0] != "my-cli" {
+		t.Fatalf("unexpected publishes %v", reg.publishes)
+	}
+}
+
+func TestRunWithNpmSkipsPublishedVersions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake npm is a shell script")
+	}
+	reg := newTestRegistry(t)
+	c := newTestConfig(t, "my-cli_darwin_arm64", "my-cli_linux_amd64")
+	c.Publish = true
+	c.PublishRegistry = reg.URL
+	if err := Run(c, testLogger); err != nil {
+		t.Fatal(err)
+	}
+
+	// the fake npm records the published tarballs
+	binDir := t.TempDir()
+	npmLog := filepath.Join(binDir, "npm.log")
+	npmScript := "#!/bin/sh\necho \"$4\" >> " + npmLog + "\n"
+	if err := os.WriteFile(filepath.Join(binDir, "npm"), []byte(npmScript), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("NPM_CONFIG_USERCONFIG", filepath.Join(binDir, ".npmrc"))
+
+	// simulate a partially failed release
+	delete(reg.packuments, "my-cli")
+	c.PublishWithNpm = true
+	c.OutputDirPath = filepath.Join(t.TempDir(), "generated-packages")
+	if err := Run(c, testLogger); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(npmLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if published := strings.Fields(string(data)); len(published) != 1 || filepath.Base(published[0]) != "my-cli-1.0.0.tgz" {
+		t.Fatalf("unexpected npm publishes %v", published)
+	}
+
+	// if the registry rejects the token for reads, npm publish (with the auth of the npm config) decides
+	reg.mu.Lock()
+	reg.readToken = "npmrc-token"
+	reg.mu.Unlock()
+	if err := os.Remove(npmLog); err != nil {
+		t.Fatal(err)
+	}
+	c.OutputDirPath = filepath.Join(t.TempDir(), "generated-packages")
+	if err := Run(c, testLogger); err != nil {
+		t.Fatal(err)
+	}
+	data, err = os.ReadFile(npmLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if published := strings.Fields(string(data)); len(published) != 3 {
+		t.Fatalf("unexpected npm publishes %v", published)
+	}
+}
+
+func TestRunDoesNotPublishMainPackageIfPlatformPackageFails(t *testing.T) {
+	reg := newTestRegistry(t)
+	reg.publishStatus = func(name string) int {
+		if name == "my-cli-linux-x64" {
+			return http.StatusBadRequest
+		}
+		return 0
+	}
+	c := newTestConfig(t, "my-cli_darwin_arm64", "my-cli_linux_amd64", "my-cli_linux_arm64", "my-cli_windows_amd64.exe")
+	c.Publish = true
+	c.PublishRegistry = reg.URL
+	c.PublishConcurrency = 2
+	if err := Run(c, testLogger); err == nil {
+		t.Fatal("expected publish error")
+	}
+	if len(reg.publishes) != 3 {
+		t.Fatalf("unexpected publishes %v", reg.publishes)
+	}
+	if _, ok := reg.packuments["my-cli"]; ok {
+		t.Fatal("main package must not be published")
+	}
+}
+
+func TestRunRetriesRetryablePublishErrors(t *testing.T) {
+	reg := newTestRegistry(t)
+	failures := map[string]int{"my-cli-linux-x64": 2, "my-cli": 1}
+	reg.publishStatus = func(name string) int {
+		if failures[name] > 0 {
+			failures[name]--
+			return http.StatusServiceUnavailable
+		}
+		return 0
+	}
+	c := newTestConfig(t, "my-cli_linux_amd64")
+	c.Publish = true
+	c.PublishRegistry = reg.URL
+	c.PublishRetries = 2
+	c.PublishRetryDelay = time.Millisecond
+	if err := Run(c, testLogger); err != nil {
+		t.Fatal(err)
+	}
+	if len(reg.publishes) != 2 {
+		t.Fatalf("unexpected publishes %v", reg.publishes)
+	}
+}
+
+func TestRunWithoutRetries(t *testing.T) {
+	reg := newTestRegistry(t)
+	attempts := 0
+	reg.publishStatus = func(name string) int {
+		attempts++
+		return http.StatusServiceUnavailable
+	}
+	c := newTestConfig(t, "my-cli_linux_amd64")
+	c.Publish = true
+	c.PublishRegistry = reg.URL
+	c.PublishRetries = 0
+	if err := Run(c, testLogger); !registry.IsRetryable(err) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if attempts != 1 {
+		t.Fatalf("got %d publish attempts, want 1", attempts)
+	}
+}
+
+func TestRunReturnsTypedPublishError(t *testing.T) {
+	reg := newTestRegistry(t)
+	reg.publishStatus = func(name string) int {
+		return http.StatusUnauthorized
+	}
+	c := newTestConfig(t, "my-cli_linux_amd64")
+	c.Publish = true
+	c.PublishRegistry = reg.URL
+	c.PublishRetries = 2
+	c.PublishRetryDelay = time.Millisecond
+	err := Run(c, testLogger)
+	var registryErr *registry.Error
+	if !errors.As(err, &registryErr) {
+		t.Fatalf("err = %v, want *registry.Error", err)
+	}
+	if registryErr.Kind != registry.ErrorKindAuth || registryErr.Package != "my-cli-linux-x64" {
+		t.Fatalf("unexpected error %+v", registryErr)
+	}
+}
+
+func TestPrefixedWriter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w := prefixedWriter(log.New(buf, "", 0), "npm")
+	if _, err := io.WriteString(w, "first line\nlast line without newline"); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// all lines are logged once Close returns
+	if got, want := buf.String(), "[npm] first line\n[npm] last line without newline\n"; got != want {
+		t.Fatalf("output = %q, want %q", got, want)
+	}
+}
+
+func TestClassifyNpmOutput(t *testing.T) {
+	tests := map[string]registry.ErrorKind{
+		"npm ERR! code E503\nnpm ERR! 503 Service Unavailable":                          registry.ErrorKindServer,
+		"npm ERR! code E403\nnpm ERR! You cannot publish over the previously published": registry.ErrorKindVersionExists,
+		"npm ERR! code ECONNRESET":                                                      registry.ErrorKindNetwork,
+		"npm ERR! code ENEEDAUTH":                                                       registry.ErrorKindAuth,
+		"npm ERR! code E401":                                                            registry.ErrorKindAuth,
+		"npm ERR! something broken":                                                     registry.ErrorKindUnknown,
+	}
+	for output, want := range tests {
+		if got := classifyNpmOutput(output); got != want {
+			t.Errorf("classifyNpmOutput(%q) = %q, want %q", output, got, want)
+		}
+	}
+}
+
+func TestDistTags(t *testing.T) {
+	reg := newTestRegistry(t)
+	c := newTestConfig(t, "my-cli_darwin_arm64", "my-cli_linux_amd64")
+	c.Publish = true
+	c.PublishRegistry = reg.URL
+	c.PackageVersion = "1.1.0-beta.1"
+	if err := Run(c, testLogger); err != nil {
+		t.Fatal(err)
+	}
+	for name, packument := range reg.packuments {
+		if packument.DistTags["beta"] != "1.1.0-beta.1" || packument.DistTags["latest"] != "" {
+			t.Fatalf("unexpected dist-tags of %s: %v", name, packument.DistTags)
+		}
+	}
+
+	tagConfig := &config.Config{BinName: "my-cli", PackageVersion: "1.1.0-beta.1", PublishRegistry: reg.URL}
+	if err := SetDistTag(tagConfig, testLogger, "next"); err != nil {
+		t.Fatal(err)
+	}
+	tagConfig.PackageVersion = ""
+	if err := RemoveDistTag(tagConfig, testLogger, "beta"); err != nil {
+		t.Fatal(err)
+	}
+	if len(reg.packuments) != 3 {
+		t.Fatalf("got %d packages, want 3", len(reg.packuments))
+	}
+	for name, packument := range reg.packuments {
+		if packument.DistTags["next"] != "1.1.0-beta.1" || packument.DistTags["beta"] != "" {
+			t.Fatalf("unexpected dist-tags of %s: %v", name, packument.DistTags)
+		}
+	}
+
+	// tags that are semver ranges are rejected before the registry is contacted
+	reg.Close()
+	tagConfig.PackageVersion = "1.1.0-beta.1"
+	for _, tag := range []string{"v1", "1.x"} {
+		if err := SetDistTag(tagConfig, testLogger, tag); err == nil || !strings.Contains(err.Error(), "invalid dist-tag") {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := RemoveDistTag(tagConfig, testLogger, tag); err == nil || !strings.Contains(err.Error(), "invalid dist-tag") {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	c.DistTag = "x"
+	if err := Run(c, testLogger); err == nil || !strings.Contains(err.Error(), "invalid dist-tag") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestInstallFallback(t *testing.T) {
+	nodePath, err := exec.LookPath("node")
+	if err != nil {
+		t.Skip("node is not installed")
+	}
+	reg := newTestRegistry(t)
+	platform, arch := helper.NodePlatformAndArch(runtime.GOOS, runtime.GOARCH)
+	c := newTestConfig(t, fmt.Sprintf("my-cli_%s_%s", runtime.GOOS, runtime.GOARCH))
+	c.Publish = true
+	c.PublishRegistry = reg.URL
+	c.InstallFallback = true
+	if err := Run(c, testLogger); err != nil {
+		t.Fatal(err)
+	}
+
+	platformPackage := fmt.Sprintf("my-cli-%s-%s", platform, arch)
+	mainPackageJson := &templates.MainPackageJson{}
+	data, err := os.ReadFile(filepath.Join(c.OutputDirPath, "my-cli", "package.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, mainPackageJson); err != nil {
+		t.Fatal(err)
+	}
+	publishedIntegrity := reg.packuments[platformPackage].Versions["1.0.0"].Dist.Integrity
+	if got := mainPackageJson.PlatformIntegrity[platformPackage]; got == "" || got != publishedIntegrity {
+		t.Fatalf("platform integrity = %q, want %q", got, publishedIntegrity)
+	}
+
+	// install only the main package, as npm install --omit=optional would do
+	installDir := filepath.Join(t.TempDir(), "node_modules", "my-cli")
+	if err := os.CopyFS(installDir, os.DirFS(filepath.Join(c.OutputDirPath, "my-cli"))); err != nil {
+		t.Fatal(err)
+	}
+	runInstall := func(env ...string) string {
+		t.Helper()
+		cmd := exec.Command(nodePath, "install.js")
+		cmd.Dir = installDir
+		cmd.Env = append(os.Environ(), env...)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("install.js failed: %v\n%s", err, out)
+		}
+		return string(out)
+	}
+
+	// failures only print a warning and do not fail the installation
+	reg.mu.Lock()
+	reg.readToken = "read-token"
+	reg.mu.Unlock()
+	tokenEnv := "npm_config_//" + strings.TrimPrefix(reg.URL, "http://") + "/:_authToken=read-token"
+	out := runInstall("npm_config_registry=" + reg.URL)
+	if !strings.Contains(out, "warning: failed to install the platform package") || !strings.Contains(out, "401") {
+		t.Fatalf("unexpected output:\n%s", out)
+	}
+	tarballPath := strings.TrimPrefix(reg.packuments[platformPackage].Versions["1.0.0"].Dist.Tarball, reg.URL+"/")
+	reg.mu.Lock()
+	tarball := reg.tarballs[tarballPath]
+	reg.tarballs[tarballPath] = append(slices.Clone(tarball), 0)
+	reg.mu.Unlock()
+	out = runInstall("npm_config_registry="+reg.URL, tokenEnv)
+	if !strings.Contains(out, "integrity mismatch") {
+		t.Fatalf("unexpected output:\n%s", out)
+	}
+	if _, err := os.Stat(filepath.Join(installDir, "fallback")); !os.IsNotExist(err) {
+		t.Fatalf("tampered package must not be installed: %v", err)
+	}
+	reg.mu.Lock()
+	reg.tarballs[tarballPath] = tarball
+	reg.mu.Unlock()
+
+	// the auth token of the registry is sent
+	runInstall("npm_config_registry="+reg.URL, tokenEnv)
+
+	binPath, err := exec.Command(nodePath, "-e", "process.stdout.write(require(process.argv[1]).getBinaryPath())", installDir).Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	binFileName := platformPackage
+	if platform == "win32" {
+		binFileName += ".exe"
+	}
+	if filepath.Base(string(binPath)) != binFileName {
+		t.Fatalf("binary path = %q, want file %q", string(binPath), binFileName)
+	}
+	if _, err := os.Stat(string(binPath)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunJsReportsMissingPlatformPackage(t *testing.T) {
+	nodePath, err := exec.LookPath("node")
+	if err != nil {
+		t.Skip("node is not installed")
+	}
+	platform, arch := helper.NodePlatformAndArch(runtime.GOOS, runtime.GOARCH)
+	c := newTestConfig(t, fmt.Sprintf("my-cli_%s_%s", runtime.GOOS, runtime.GOARCH))
+	if err := Run(c, testLogger); err != nil {
+		t.Fatal(err)
+	}
+
+	installDir := filepath.Join(t.TempDir(), "node_modules", "my-cli")
+	if err := os.CopyFS(installDir, os.DirFS(filepath.Join(c.OutputDirPath, "my-cli"))); err != nil {
+		t.Fatal(err)
+	}
+	out, err := exec.Command(nodePath, filepath.Join(installDir, "run.js")).CombinedOutput()
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 3 {
+		t.Fatalf("expected exit code 3, got %v\n%s", err, out)
+	}
+	for _, want := range []string{"supported platforms: " + platform + "-" + arch, "--omit=optional"} {
+		if !strings.Contains(string(out), want) {
+			t.Fatalf("output does not contain %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestRunJsUsesBinaryPathEnv(t *testing.T) {
+	nodePath, err := exec.LookPath("node")
+	if err != nil {
+		t.Skip("node is not installed")
+	}
+	if runtime.GOOS == "windows" {
+		t.Skip("test binary is a shell script")
+	}
+	c := newTestConfig(t, fmt.Sprintf("my-cli_%s_%s", runtime.GOOS, runtime.GOARCH))
+	if err := Run(c, testLogger); err != nil {
+		t.Fatal(err)
+	}
+	if c.BinaryPathEnv != "MY_CLI_BINARY_PATH" {
+		t.Fatalf("unexpected binary path env: %s", c.BinaryPathEnv)
+	}
+
+	// only the main package is installed, the override skips the package resolution
+	installDir := filepath.Join(t.TempDir(), "node_modules", "my-cli")
+	if err := os.CopyFS(installDir, os.DirFS(filepath.Join(c.OutputDirPath, "my-cli"))); err != nil {
+		t.Fatal(err)
+	}
+	localBin := filepath.Join(t.TempDir(), "local-build")
+	if err := os.WriteFile(localBin, []byte("#!/bin/sh\necho local build\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command(nodePath, filepath.Join(installDir, "run.js"))
+	cmd.Env = append(os.Environ(), "MY_CLI_BINARY_PATH="+localBin)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("run.js failed: %v\n%s", err, out)
+	}
+	if string(out) != "local build\n" {
+		t.Fatalf("unexpected output: %q", out)
+	}
+}
+
+func TestRunJsPropagatesExitStatus(t *testing.T) {
+	nodePath, err := exec.LookPath("node")
+	if err != nil {
+		t.Skip("node is not installed")
+	}
+	if runtime.GOOS == "windows" {
+		t.Skip("test binary is a shell script")
+	}
+	c := newTestConfig(t, fmt.Sprintf("my-cli_%s_%s", runtime.GOOS, runtime.GOARCH))
+	if err := Run(c, testLogger); err != nil {
+		t.Fatal(err)
+	}
+	runJs := filepath.Join(c.OutputDirPath, "my-cli", "run.js")
+	binDir := t.TempDir()
+	writeBin := func(name, content string, perm os.FileMode) string {
+		binPath := filepath.Join(binDir, name)
+		if err := os.WriteFile(binPath, []byte(content), perm); err != nil {
+			t.Fatal(err)
+		}
+		return binPath
+	}
+	tests := []struct {
+		name     string
+		binPath  string
+		exitCode int
+		signal   string
+		output   string
+	}{
+		{"exit code", writeBin("exit", "#!/bin/sh\nexit 42\n", 0755), 42, "", ""},
+		{"signal", writeBin("signal", "#!/bin/sh\nkill -TERM $$\n", 0755), -1, "signal: terminated", ""},
+		{"not found", filepath.Join(binDir, "missing"), 127, "", "binary does not exist (ENOENT)"},
+		{"not executable", writeBin("no-exec", "#!/bin/sh\n", 0644), 126, "", "binary is not executable (EACCES)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := exec.Command(nodePath, runJs)
+			cmd.Env = append(os.Environ(), "MY_CLI_BINARY_PATH="+tt.binPath)
+			out, err := cmd.CombinedOutput()
+			var exitErr *exec.ExitError
+			if !errors.As(err, &exitErr) || exitErr.ExitCode() != tt.exitCode {
+				t.Fatalf("expected exit code %d, got %v\n%s", tt.exitCode, err, out)
+			}
+			if tt.signal != "" && exitErr.String() != tt.signal {
+				t.Fatalf("expected %q, got %q", tt.signal, exitErr.String())
+			}
+			if !strings.Contains(string(out), tt.output) {
+				t.Fatalf("output does not contain %q:\n%s", tt.output, out)
+			}
+		})
+	}
+}
+
+func TestDirectBinLink(t *testing.T) {
+	nodePath, err := exec.LookPath("node")
+	if err != nil {
+		t.Skip("node is not installed")
+	}
+	if runtime.GOOS == "windows" {
+		t.Skip("direct bin linking is not supported on Windows")
+	}
+	platform, arch := helper.NodePlatformAndArch(runtime.GOOS, runtime.GOARCH)
+	binFile := fmt.Sprintf("my-cli_%s_%s", runtime.GOOS, runtime.GOARCH)
+	c := newTestConfig(t, binFile)
+	c.DirectBinLink = true
+	if err := Run(c, testLogger); err != nil {
+		t.Fatal(err)
+	}
+
+	nodeModules := filepath.Join(t.TempDir(), "node_modules")
+	for _, name := range []string{"my-cli", fmt.Sprintf("my-cli-%s-%s", platform, arch)} {
+		if err := os.CopyFS(filepath.Join(nodeModules, name), os.DirFS(filepath.Join(c.OutputDirPath, name))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	runJs := filepath.Join(nodeModules, "my-cli", "run.js")
+	linkJs := func(userAgent string) {
+		t.Helper()
+		cmd := exec.Command(nodePath, "link.js")
+		cmd.Dir = filepath.Join(nodeModules, "my-cli")
+		cmd.Env = append(os.Environ(), "npm_config_user_agent="+userAgent)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("link.js failed: %v\n%s", err, out)
+		}
+	}
+
+	linkJs("pnpm/9.0.0 npm/? node/v20.0.0 linux x64")
+	if data, _ := os.ReadFile(runJs); !strings.HasPrefix(string(data), "#!/usr/bin/env node") {
+		t.Fatal("run.js must be kept for pnpm")
+	}
+
+	linkJs("npm/10.0.0 node/v20.0.0 linux x64")
+	if data, _ := os.ReadFile(runJs); strings.HasPrefix(string(data), "#!/usr/bin/env node") {
+		t.Fatal("run.js was not replaced with the binary")
+	}
+	out, err := exec.Command(runJs).Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != binFile+"\n" {
+		t.Fatalf("unexpected output: %q", out)
+	}
+}
+
+func TestRunMultipleBinaries(t *testing.T) {
+	goos, goarch := runtime.GOOS, runtime.GOARCH
+	c := newTestConfig(t,
+		fmt.Sprintf("my-cli_%s_%s", goos, goarch), fmt.Sprintf("my-daemon_%s_%s", goos, goarch),
+		"my-cli_linux_arm64", "my-daemon_linux_arm64", "unrelated_linux_arm64")
+	c.Binaries = []config.Binary{{Name: "my-cli", Match: "my-cli_*"}, {Name: "my-daemon", Match: "my-daemon_*"}}
+	if err := Run(c, testLogger); err != nil {
+		t.Fatal(err)
+	}
+
+	platform, arch := helper.NodePlatformAndArch(goos, goarch)
+	platformPackage := fmt.Sprintf("my-cli-%s-%s", platform, arch)
+	for _, file := range []string{"my-cli-linux-arm64/my-cli", "my-cli-linux-arm64/my-daemon", "my-cli/run.js", "my-cli/run-my-daemon.js"} {
+		if _, err := os.Stat(filepath.Join(c.OutputDirPath, file)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	nodePath, err := exec.LookPath("node")
+	if err != nil || goos == "windows" {
+		return
+	}
+	nodeModules := filepath.Join(t.TempDir(), "node_modules")
+	for _, name := range []string{"my-cli", platformPackage} {
+		if err := os.CopyFS(filepath.Join(nodeModules, name), os.DirFS(filepath.Join(c.OutputDirPath, name))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for launcher, want := range map[string]string{"run.js": "my-cli", "run-my-daemon.js": "my-daemon"} {
+		out, err := exec.Command(nodePath, filepath.Join(nodeModules, "my-cli", launcher)).CombinedOutput()
+		if err != nil {
+			t.Fatalf("%s failed: %v\n%s", launcher, err, out)
+		}
+		if wantOut := fmt.Sprintf("%s_%s_%s\n", want, goos, goarch); string(out) != wantOut {
+			t.Fatalf("%s output = %q, want %q", launcher, out, wantOut)
+		}
+	}
+
+	// the JS API spawns the main binary unless another binary is selected
+	script := `const api = require(process.argv[1])
+const binName = process.argv[2] || undefined
+process.stdout.write(api.spawnSync([], { binName, encoding: 'utf8' }).stdout)
+api.spawn([], { binName, stdio: 'inherit' })`
+	for binName, want := range map[string]string{"": "my-cli", "my-daemon": "my-daemon"} {
+		out, err := exec.Command(nodePath, "-e", script, filepath.Join(nodeModules, "my-cli"), binName).CombinedOutput()
+		if err != nil {
+			t.Fatalf("spawn %q failed: %v\n%s", binName, err, out)
+		}
+		wantLine := fmt.Sprintf("%s_%s_%s\n", want, goos, goarch)
+		if string(out) != wantLine+wantLine {
+			t.Fatalf("spawn %q output = %q, want %q twice", binName, out, wantLine)
+		}
+	}
+}
+
+func TestCreatePlanRequiresAllBinaries(t *testing.T) {
+	c := newTestConfig(t, "my-cli_linux_amd64", "my-daemon_linux_amd64", "my-cli_darwin_arm64")
+	c.Binaries = []config.Binary{{Name: "my-cli", Match: "my-cli_*"}, {Name: "my-daemon", Match: "my-daemon_*"}}
+	_, err := CreatePlan(c, testLogger)
+	if err == nil || !strings.Contains(err.Error(), "binary my-daemon is missing for darwin-arm64") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestCreatePlanWithPlatforms(t *testing.T) {
+	c := newTestConfig(t)
+	projectDir := t.TempDir()
+	writeFile := func(name string) string {
+		filePath := filepath.Join(projectDir, name)
+		if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filePath, []byte("#!/bin/sh\n"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		return filePath
+	}
+	// the binary is preferred over files that come first in the directory
+	writeFile("out-lnx/LICENSE")
+	linuxBin := writeFile("out-lnx/my-cli")
+	macBin := writeFile("out-mac")
+	c.InputBinDirPath = ""
+	c.Platforms = []config.Platform{
+		{OS: "linux", CPU: "amd64", Libc: "musl", Path: filepath.Join(projectDir, "out-lnx")},
+		{OS: "darwin", CPU: "arm64", Path: macBin},
+	}
+	plan, err := CreatePlan(c, testLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	packages := plan.PlatformPackages()
+	if len(packages) != 2 {
+		t.Fatalf("got %d platform packages, want 2", len(packages))
+	}
+	if packages[0].Name != "my-cli-linux-x64-musl" || packages[0].Libc != "musl" || packages[0].Source != linuxBin {
+		t.Fatalf("unexpected linux package: %+v", packages[0])
+	}
+	if packages[1].Name != "my-cli-darwin-arm64" || packages[1].Source != macBin {
+		t.Fatalf("unexpected darwin package: %+v", packages[1])
+	}
+
+	c.Platforms = append(c.Platforms, config.Platform{OS: "win32", CPU: "x64", Path: filepath.Join(projectDir, "missing")})
+	if _, err := CreatePlan(c, testLogger); err == nil || !strings.Contains(err.Error(), "platform win32-x64") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestCreatePlanFiltersPlatforms(t *testing.T) {
+	c := newTestConfig(t, "my-cli_linux_amd64", "my-cli_linux_mips", "my-cli_plan9_amd64", "my-cli_darwin_arm64")
+	c.ExcludePlatforms = []string{"plan9-*", "*-mips*"}
+	c.RequiredPlatforms = []string{"linux-x64", "darwin-arm64"}
+	plan, err := CreatePlan(c, testLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0)
+	for _, pkg := range plan.PlatformPackages() {
+		names = append(names, pkg.Name)
+	}
+	if want := []string{"my-cli-darwin-arm64", "my-cli-linux-x64"}; !slices.Equal(names, want) {
+		t.Fatalf("packages = %v, want %v", names, want)
+	}
+
+	// Go names are accepted as well
+	c = newTestConfig(t, "my-cli_linux_amd64", "my-cli_windows_amd64.exe", "my-cli_darwin_arm64")
+	c.IncludePlatforms = []string{"windows-amd64", "darwin-*"}
+	plan, err = CreatePlan(c, testLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names = names[:0]
+	for _, pkg := range plan.PlatformPackages() {
+		names = append(names, pkg.Name)
+	}
+	if want := []string{"my-cli-darwin-arm64", "my-cli-win32-x64"}; !slices.Equal(names, want) {
+		t.Fatalf("packages = %v, want %v", names, want)
+	}
+
+	c = newTestConfig(t, "my-cli_linux_amd64", "my-cli_darwin_arm64")
+	c.IncludePlatforms = []string{"linux-*"}
+	c.RequiredPlatforms = []string{"linux-amd64", "darwin-arm64", "windows-amd64"}
+	_, err = CreatePlan(c, testLogger)
+	if err == nil || err.Error() != "required platforms are missing: darwin-arm64, win32-x64" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestCreatePlanSelectsVariant(t *testing.T) {
+	tests := map[string]string{
+		config.VariantPolicyLowest:  "my-cli_linux_arm_6",
+		config.VariantPolicyHighest: "my-cli_linux_arm_7",
+	}
+	for policy, wantFile := range tests {
+		c := newTestConfig(t, "my-cli_linux_arm_6", "my-cli_linux_arm_7", "my-cli_linux_amd64_v1", "my-cli_linux_amd64_v3")
+		c.VariantPolicy = policy
+		plan, err := CreatePlan(c, testLogger)
+		if err != nil {
+			t.Fatal(err)
+		}
+		packages := plan.PlatformPackages()
+		if len(packages) != 2 {
+			t.Fatalf("%s: got %d platform packages, want 2", policy, len(packages))
+		}
+		if packages[1].Name != "my-cli-linux-arm" || filepath.Base(packages[1].Source) != wantFile {
+			t.Fatalf("%s: unexpected package %+v", policy, packages[1])
+		}
+	}
+
+	c := newTestConfig(t, "my-cli_linux_arm_6", "my-cli_linux_arm_7")
+	c.VariantPolicy = config.VariantPolicyError
+	if _, err := CreatePlan(c, testLogger); err == nil || !strings.Contains(err.Error(), "multiple variants of my-cli for linux-arm") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// a version after the arch is not a variant
+	c = newTestConfig(t, "my-cli_linux_arm_5.0.1")
+	plan, err := CreatePlan(c, testLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if packages := plan.PlatformPackages(); len(packages) != 1 || packages[0].Name != "my-cli-linux-arm" || packages[0].Variant == "5" {
+		t.Fatalf("unexpected packages %+v", packages)
+	}
+}
+
+func TestRunReportsDuplicatePlatforms(t *testing.T) {
+	c := newTestConfig(t, "foo_linux_amd64", "foo-linux-x86_64", "foo_darwin_arm64", "foo-darwin-arm64.tar", "foo_windows_amd64.exe")
+	if err := os.MkdirAll(c.OutputDirPath, 0755); err != nil {
+		t.Fatal(err)
+	}
+	marker := filepath.Join(c.OutputDirPath, "marker")
+	if err := os.WriteFile(marker, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	err := Run(c, testLogger)
+	if err == nil {
+		t.Fatal("expected duplicate platform error")
+	}
+	for _, want := range []string{"linux-x64", "foo_linux_amd64", "foo-linux-x86_64", "darwin-arm64", "foo_darwin_arm64", "foo-darwin-arm64.tar"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("error does not contain %q: %v", want, err)
+		}
+	}
+	if _, err := os.Stat(marker); err != nil {
+		t.Fatalf("output directory must not be touched: %v", err)
+	}
+
+	c.PreferSources = []string{"*_linux_*", "*-darwin-*"}
+	plan, err := CreatePlan(c, testLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sources := make([]string, 0)
+	for _, pkg := range plan.PlatformPackages() {
+		sources = append(sources, filepath.Base(pkg.Source))
+	}
+	if want := []string{"foo-darwin-arm64.tar", "foo_linux_amd64", "foo_windows_amd64.exe"}; !slices.Equal(sources, want) {
+		t.Fatalf("sources = %v, want %v", sources, want)
+	}
+}
+
+// writeElfBinary writes a minimal 64-bit ELF executable header for the machine.
+func writeElfBinary(t *testing.T, filePath string, machine elf.Machine) {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	ident := [elf.EI_NIDENT]byte{0x7f, 'E', 'L', 'F', byte(elf.ELFCLASS64), byte(elf.ELFDATA2LSB), byte(elf.EV_CURRENT)}
+	_ = binary.Write(buf, binary.LittleEndian, elf.Header64{
+		Ident:     ident,
+		Type:      uint16(elf.ET_EXEC),
+		Machine:   uint16(machine),
+		Version:   uint32(elf.EV_CURRENT),
+		Ehsize:    64,
+		Phentsize: 56,
+		Shentsize: 64,
+	})
+	if err := os.WriteFile(filePath, buf.Bytes(), 0755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunChecksPlatformAgainstBinaryHeader(t *testing.T) {
+	c := newTestConfig(t)
+	// the file name claims arm64, but the binary is an x86-64 executable
+	writeElfBinary(t, filepath.Join(c.InputBinDirPath, "my-cli_linux_arm64"), elf.EM_X86_64)
+	c.FailOnPlatformMismatch = true
+	err := Run(c, testLogger)
+	if err == nil || !strings.Contains(err.Error(), "expected linux-arm64 but binary header indicates linux-x64") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(c.OutputDirPath); !os.IsNotExist(err) {
+		t.Fatalf("no package must be created: %v", err)
+	}
+
+	// a binary without os/arch in its name is released for the platform of its header
+	c = newTestConfig(t)
+	writeElfBinary(t, filepath.Join(c.InputBinDirPath, "my-cli"), elf.EM_AARCH64)
+	if err := Run(c, testLogger); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(c.OutputDirPath, "my-cli-linux-arm64", "my-cli-linux-arm64")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeTarGz(t *testing.T, archivePath string, files map[string]string) {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	gzWriter := gzip.NewWriter(buf)
+	tarWriter := tar.NewWriter(gzWriter)
+	for name, content := range files {
+		if err := tarWriter.WriteHeader(&tar.Header{Name: name, Mode: 0755, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tarWriter.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tarWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gzWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(archivePath, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func sha256File(t *testing.T, filePath string) string {
+	t.Helper()
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestRunVerifiesArchiveChecksums(t *testing.T) {
+	c := newTestConfig(t)
+	archivePath := filepath.Join(c.InputBinDirPath, "my-cli_1.0.0_linux_amd64.tar.gz")
+	writeTarGz(t, archivePath, map[string]string{"my-cli": "#!/bin/sh\n", "README.md": "# my-cli\n"})
+	c.ChecksumsFile = filepath.Join(c.InputBinDirPath, "checksums.txt")
+	checksums := sha256File(t, archivePath) + "  my-cli_1.0.0_linux_amd64.tar.gz\n"
+	if err := os.WriteFile(c.ChecksumsFile, []byte(checksums), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Run(c, testLogger); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(c.OutputDirPath, "my-cli-linux-x64", "my-cli-linux-x64")); err != nil {
+		t.Fatal(err)
+	}
+
+	// a tampered archive is rejected before it is extracted
+	writeTarGz(t, archivePath, map[string]string{"my-cli": "#!/bin/sh\necho tampered\n"})
+	c.OutputDirPath = filepath.Join(t.TempDir(), "generated-packages")
+	err := Run(c, testLogger)
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch for "+archivePath) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(c.OutputDirPath); !os.IsNotExist(err) {
+		t.Fatalf("no package must be created: %v", err)
+	}
+}
+
+func TestRunVerifiesGoReleaserChecksums(t *testing.T) {
+	projectDir := t.TempDir()
+	distDir := filepath.Join(projectDir, "dist")
+	binDir := filepath.Join(distDir, "my-cli_linux_arm64")
+	if err := os.MkdirAll(binDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeElfBinary(t, filepath.Join(binDir, "my-cli"), elf.EM_AARCH64)
+	archivePath := filepath.Join(distDir, "my-cli_1.2.3_linux_arm64.tar.gz")
+	writeTarGz(t, archivePath, map[string]string{"my-cli": "binary"})
+	writeChecksums := func(binChecksum string) {
+		checksums := sha256File(t, archivePath) + "  my-cli_1.2.3_linux_arm64.tar.gz\n"
+		if binChecksum != "" {
+			checksums += binChecksum + "  my-cli_linux_arm64/my-cli\n"
+		}
+		if err := os.WriteFile(filepath.Join(distDir, "checksums.txt"), []byte(checksums), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	binChecksum := sha256File(t, filepath.Join(binDir, "my-cli"))
+	writeChecksums(binChecksum)
+	writeArtifacts := func(checksum string) {
+		artifacts := []map[string]any{
+			{"name": "my-cli", "path": "dist/my-cli_linux_arm64/my-cli", "goos": "linux", "goarch": "arm64", "type": "Binary",
+				"extra": map[string]any{"Binary": "my-cli", "Checksum": checksum}},
+			{"name": "my-cli_1.2.3_linux_arm64.tar.gz", "path": "dist/my-cli_1.2.3_linux_arm64.tar.gz", "goos": "linux", "goarch": "arm64", "type": "Archive"},
+			{"name": "checksums.txt", "path": "dist/checksums.txt", "type": "Checksum"},
+		}
+		data, err := json.Marshal(artifacts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(distDir, "artifacts.json"), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeArtifacts("sha256:" + sha256File(t, filepath.Join(binDir, "my-cli")))
+	if err := os.WriteFile(filepath.Join(distDir, "metadata.json"), []byte(`{"project_name":"my-cli","tag":"v1.2.3","version":"1.2.3"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := &config.Config{
+		BinName:             "my-cli",
+		InputBinDirPath:     distDir,
+		GoReleaserArtifacts: true,
+		ChecksumsFile:       filepath.Join(distDir, "checksums.txt"),
+		OutputDirPath:       filepath.Join(t.TempDir(), "generated-packages"),
+		PublishRegistry:     config.DefaultPublishRegistry,
+	}
+	if err := Run(c, testLogger); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(c.OutputDirPath, "my-cli-linux-arm64", "my-cli-linux-arm64")); err != nil {
+		t.Fatal(err)
+	}
+
+	// the checksum in artifacts.json is cross-checked
+	writeArtifacts("sha256:" + strings.Repeat("0", 64))
+	c.OutputDirPath = filepath.Join(t.TempDir(), "generated-packages")
+	err := Run(c, testLogger)
+	if err == nil || !strings.Contains(err.Error(), "artifacts.json: checksum mismatch for "+filepath.Join(binDir, "my-cli")) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// a tampered dist directory with a matching artifacts.json is rejected by the checksums file
+	writeElfBinary(t, filepath.Join(binDir, "my-cli"), elf.EM_X86_64)
+	writeArtifacts("sha256:" + sha256File(t, filepath.Join(binDir, "my-cli")))
+	err = Run(c, testLogger)
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch for "+filepath.Join(binDir, "my-cli")+": expected "+binChecksum) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// binaries must be listed in the checksums file even if artifacts.json contains their checksum
+	writeChecksums("")
+	err = Run(c, testLogger)
+	if err == nil || !strings.Contains(err.Error(), "no checksum found for "+filepath.Join(binDir, "my-cli")) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestRunReleasesLibcVariants(t *testing.T) {
+	c := newTestConfig(t, "my-cli_linux_amd64", "my-cli_linux_amd64_musl", "my-cli_darwin_arm64")
+	if err := Run(c, testLogger); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string][]string{"my-cli-linux-x64": nil, "my-cli-linux-x64-musl": {"musl"}} {
+		binPackageJson := &templates.BinPackageJson{}
+		data, err := os.ReadFile(filepath.Join(c.OutputDirPath, name, "package.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal(data, binPackageJson); err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(binPackageJson.Libc, want) {
+			t.Fatalf("%s: libc = %v, want %v", name, binPackageJson.Libc, want)
+		}
+	}
+
+	// a single musl binary that is left after filtering is released for all libc variants
+	c = newTestConfig(t, "my-cli_linux_amd64", "my-cli_linux_amd64_musl")
+	c.IncludePlatforms = []string{"linux-x64-musl"}
+	plan, err := CreatePlan(c, testLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if packages := plan.PlatformPackages(); len(packages) != 1 || packages[0].Name != "my-cli-linux-x64" || packages[0].Libc != "" {
+		t.Fatalf("unexpected packages %+v", packages)
+	}
+}
+
+func TestRunJsSelectsLibcVariant(t *testing.T) {
+	nodePath, err := exec.LookPath("node")
+	if err != nil {
+		t.Skip("node is not installed")
+	}
+	if runtime.GOOS != "linux" {
+		t.Skip("libc variants are only released for linux")
+	}
+	goarch := runtime.GOARCH
+	_, arch := helper.NodePlatformAndArch(runtime.GOOS, goarch)
+	c := newTestConfig(t, "my-cli_linux_"+goarch, "my-cli_linux_"+goarch+"_musl")
+	if err := Run(c, testLogger); err != nil {
+		t.Fatal(err)
+	}
+	nodeModules := filepath.Join(t.TempDir(), "node_modules")
+	for _, name := range []string{"my-cli", "my-cli-linux-" + arch, "my-cli-linux-" + arch + "-musl"} {
+		if err := os.CopyFS(filepath.Join(nodeModules, name), os.DirFS(filepath.Join(c.OutputDirPath, name))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the preloaded script fakes the libc that is reported by Node.js
+	preload := filepath.Join(t.TempDir(), "libc.js")
+	preloadScript := "const header = process.env.FAKE_GLIBC ? { glibcVersionRuntime: process.env.FAKE_GLIBC } : {}\n" +
+		"process.report.getReport = () => ({ header })\n"
+	if err := os.WriteFile(preload, []byte(preloadScript), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for env, want := range map[string]string{"FAKE_GLIBC=": "my-cli_linux_" + goarch + "_musl\n", "FAKE_GLIBC=2.36": "my-cli_linux_" + goarch + "\n"} {
+		cmd := exec.Command(nodePath, "--require", preload, filepath.Join(nodeModules, "my-cli", "run.js"))
+		cmd.Env = append(os.Environ(), env)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("%s: run.js failed: %v\n%s", env, err, out)
+		}
+		if string(out) != want {
+			t.Fatalf("%s: output = %q, want %q", env, out, want)
+		}
+	}
+}
+
+func writeZip(t *testing.T, archivePath string, files map[string]string) {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	zipWriter := zip.NewWriter(buf)
+	for name, content := range files {
+		w, err := zipWriter.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zipWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(archivePath, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunWithArchives(t *testing.T) {
+	goos, goarch := runtime.GOOS, runtime.GOARCH
+	platform, arch := helper.NodePlatformAndArch(goos, goarch)
+	c := newTestConfig(t, "README.md")
+	c.ArchiveExtraFiles = []string{"LICENSE"}
+	hostArchive := filepath.Join(c.InputBinDirPath, fmt.Sprintf("my-cli_1.0.0_%s_%s.tar.gz", goos, goarch))
+	writeTarGz(t, hostArchive, map[string]string{
+		"my-cli_1.0.0/my-cli":  "#!/bin/sh\necho from archive\n",
+		"my-cli_1.0.0/LICENSE": "MIT\n",
+	})
+	windowsArchive := filepath.Join(c.InputBinDirPath, "my-cli_1.0.0_windows_arm64.zip")
+	writeZip(t, windowsArchive, map[string]string{"my-cli.exe": "MZ"})
+
+	plan, err := CreatePlan(c, testLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sources := make(map[string]*PackagePlan)
+	for _, pkg := range plan.PlatformPackages() {
+		sources[pkg.Source] = pkg
+	}
+	hostPackage, windowsPackage := sources[hostArchive], sources[windowsArchive]
+	if len(sources) != 2 || hostPackage == nil || windowsPackage == nil {
+		t.Fatalf("unexpected packages %v", sources)
+	}
+	if hostPackage.Name != fmt.Sprintf("my-cli-%s-%s", platform, arch) || !slices.Equal(hostPackage.ExtraFiles, []string{"LICENSE"}) {
+		t.Fatalf("unexpected host package %+v", hostPackage)
+	}
+	if windowsPackage.Name != "my-cli-win32-arm64" || windowsPackage.BinFileName != "my-cli-win32-arm64.exe" {
+		t.Fatalf("unexpected windows package %+v", windowsPackage)
+	}
+
+	if err := Run(c, testLogger); err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range []string{
+		filepath.Join(hostPackage.Name, "LICENSE"),
+		filepath.Join(hostPackage.Name, hostPackage.BinFileName),
+		filepath.Join(windowsPackage.Name, windowsPackage.BinFileName),
+	} {
+		if _, err := os.Stat(filepath.Join(c.OutputDirPath, file)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// a corrupt archive fails the release instead of dropping the platform
+	corruptArchive := filepath.Join(c.InputBinDirPath, "my-cli_1.0.0_darwin_arm64.tar.gz")
+	if err := os.WriteFile(corruptArchive, []byte("not a gzip file"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CreatePlan(c, testLogger); err == nil || !strings.Contains(err.Error(), "could not extract archive "+corruptArchive) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := os.Remove(corruptArchive); err != nil {
+		t.Fatal(err)
+	}
+
+	nodePath, err := exec.LookPath("node")
+	if err != nil || goos == "windows" {
+		return
+	}
+	nodeModules := filepath.Join(t.TempDir(), "node_modules")
+	for _, name := range []string{"my-cli", hostPackage.Name} {
+		if err := os.CopyFS(filepath.Join(nodeModules, name), os.DirFS(filepath.Join(c.OutputDirPath, name))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	out, err := exec.Command(nodePath, filepath.Join(nodeModules, "my-cli", "run.js")).CombinedOutput()
+	if err != nil || string(out) != "from archive\n" {
+		t.Fatalf("run.js failed: %v\n%s", err, out)
+	}
+}
+
+func TestRunWithGoReleaserArtifacts(t *testing.T) {
+	projectDir := t.TempDir()
+	distDir := filepath.Join(projectDir, "dist")
+	artifacts := make([]map[string]any, 0)
+	addArtifact := func(artifactType, artifactPath, goos, goarch string, extra map[string]any) string {
+		filePath := filepath.Join(projectDir, artifactPath)
+		if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filePath, []byte("#!/bin/sh\necho "+artifactPath+"\n"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		artifacts = append(artifacts, map[string]any{"name": filepath.Base(artifactPath), "path": artifactPath,
+			"goos": goos, "goarch": goarch, "type": artifactType, "extra": extra})
+		data, err := json.Marshal(artifacts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(distDir, "artifacts.json"), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return filePath
+	}
+	binaryExtra := map[string]any{"Binary": "my-cli"}
+	linuxBin := addArtifact("Binary", "dist/my-cli_linux_amd64_v1/my-cli", "linux", "amd64", binaryExtra)
+	windowsBin := addArtifact("Binary", "dist/my-cli_windows_amd64_v1/my-cli.exe", "windows", "amd64", map[string]any{"Binary": "my-cli", "Ext": ".exe"})
+	universalBin := addArtifact("Universal Binary", "dist/my-cli_darwin_all/my-cli", "darwin", "all", binaryExtra)
+	addArtifact("Archive", "dist/my-cli_1.2.3_linux_amd64.tar.gz", "linux", "amd64", nil)
+	if err := os.WriteFile(filepath.Join(distDir, "metadata.json"), []byte(`{"project_name":"my-cli","tag":"v1.2.3","version":"1.2.3"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	newConfig := func() *config.Config {
+		return &config.Config{
+			BinName:             "my-cli",
+			InputBinDirPath:     distDir,
+			GoReleaserArtifacts: true,
+			OutputDirPath:       filepath.Join(t.TempDir(), "generated-packages"),
+			PublishRegistry:     config.DefaultPublishRegistry,
+		}
+	}
+	packageSources := func(c *config.Config) map[string]string {
+		t.Helper()
+		plan, err := CreatePlan(c, testLogger)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sources := make(map[string]string)
+		for _, pkg := range plan.PlatformPackages() {
+			sources[pkg.Name] = pkg.Source
+		}
+		return sources
+	}
+
+	// the universal binary is released for both macOS architectures
+	c := newConfig()
+	want := map[string]string{
+		"my-cli-darwin-arm64": universalBin,
+		"my-cli-darwin-x64":   universalBin,
+		"my-cli-linux-x64":    linuxBin,
+		"my-cli-win32-x64":    windowsBin,
+	}
+	if got := packageSources(c); !maps.Equal(got, want) {
+		t.Fatalf("packages = %v, want %v", got, want)
+	}
+	if err := Run(c, testLogger); err != nil {
+		t.Fatal(err)
+	}
+	if c.PackageVersion != "1.2.3" {
+		t.Fatalf("version = %q, want 1.2.3", c.PackageVersion)
+	}
+	for _, file := range []string{"my-cli-darwin-x64/my-cli-darwin-x64", "my-cli-win32-x64/my-cli-win32-x64.exe", "my-cli/package.json"} {
+		if _, err := os.Stat(filepath.Join(c.OutputDirPath, file)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// single arch binaries that were kept next to the universal binary are preferred
+	darwinBin := addArtifact("Binary", "dist/my-cli_darwin_arm64_v8.0/my-cli", "darwin", "arm64", binaryExtra)
+	want["my-cli-darwin-arm64"] = darwinBin
+	if got := packageSources(newConfig()); !maps.Equal(got, want) {
+		t.Fatalf("packages = %v, want %v", got, want)
+	}
+}
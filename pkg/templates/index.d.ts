@@ -0,0 +1,33 @@
+import { ChildProcess, SpawnOptions, SpawnSyncOptions, SpawnSyncReturns } from 'child_process'
+
+export interface PlatformPackageNotFoundError extends Error {
+  code: 'ERR_PLATFORM_PACKAGE_NOT_FOUND'
+  platform: string
+  packageNames: string[]
+  supportedPlatforms: string[]
+}
+
+/**
+ * Returns the absolute path of the binary for the current platform.
+ * binName selects one of the bin entries of the package and defaults to the main binary.
+ * The environment variable in the binaryPathEnv field of package.json overrides the path.
+ * Throws a PlatformPackageNotFoundError if no matching platform package is installed.
+ */
+export declare function getBinaryPath (binName?: string): string
+
+export interface BinaryOptions {
+  /**
+   * Selects one of the bin entries of the package like getBinaryPath and defaults to the main binary.
+   */
+  binName?: string
+}
+
+/**
+ * Spawns the binary (the main binary unless options.binName is set) with the given arguments.
+ */
+export declare function spawn (args?: readonly string[], options?: SpawnOptions & BinaryOptions): ChildProcess
+
+/**
+ * Synchronously spawns the binary (the main binary unless options.binName is set) with the given arguments.
+ */
+export declare function spawnSync (args?: readonly string[], options?: SpawnSyncOptions & BinaryOptions): SpawnSyncReturns<string | Buffer>
@@ -0,0 +1,73 @@
+const cp = require('child_process')
+const pkg = require('./package.json')
+const { currentPlatform, packageNames } = require('./platform.js')
+
+function resolvePackage (name) {
+  try {
+    return require.resolve(name)
+  } catch (e) {}
+  // platform package downloaded by install.js
+  try {
+    return require.resolve('./fallback/' + name)
+  } catch (e) {}
+  return null
+}
+
+// name of the main binary, which is the main file of the platform package
+const mainBinName = Object.keys(pkg.bin).find(name => pkg.bin[name] === 'run.js')
+
+// returns the module path of the binary in the platform package
+function binaryModule (name, binName) {
+  if (binName === mainBinName) {
+    return name
+  }
+  return name + '/' + binName + (process.platform === 'win32' ? '.exe' : '')
+}
+
+function getBinaryPath (binName) {
+  binName = binName || mainBinName
+  if (!pkg.bin[binName]) {
+    throw new Error('unknown binary: ' + binName)
+  }
+  // binary override for debugging and air-gapped setups
+  const envName = (pkg.binaryPathEnv || {})[binName]
+  const envPath = envName && process.env[envName]
+  if (envPath) {
+    return envPath
+  }
+  const names = packageNames(pkg)
+  for (const name of names) {
+    const binPath = resolvePackage(binaryModule(name, binName))
+    if (binPath) {
+      return binPath
+    }
+  }
+  const err = new Error('no platform package found for ' + currentPlatform() + ' (tried ' + names.join(', ') + ')')
+  err.code = 'ERR_PLATFORM_PACKAGE_NOT_FOUND'
+  err.platform = currentPlatform()
+  err.packageNames = names
+  err.supportedPlatforms = pkg.supportedPlatforms || []
+  throw err
+}
+
+// splits the binName option, which selects the binary like getBinaryPath, from the options of child_process
+function splitOptions (options) {
+  const { binName, ...spawnOptions } = options || {}
+  return [getBinaryPath(binName), spawnOptions]
+}
+
+function spawn (args, options) {
+  const [binPath, spawnOptions] = splitOptions(options)
+  return cp.spawn(binPath, args || [], spawnOptions)
+}
+
+function spawnSync (args, options) {
+  const [binPath, spawnOptions] = splitOptions(options)
+  return cp.spawnSync(binPath, args || [], spawnOptions)
+}
+
+module.exports = {
+  getBinaryPath,
+  spawn,
+  spawnSync
+}
@@ -0,0 +1,174 @@
+#!/usr/bin/env node
+
+// Downloads the platform package from the registry if the package manager did not install it
+// (e.g. npm install --omit=optional).
+
+const process = require('process')
+const crypto = require('crypto')
+const fs = require('fs')
+const os = require('os')
+const path = require('path')
+const zlib = require('zlib')
+const pkg = require('./package.json')
+const { packageNames } = require('./platform.js')
+
+// reads the npm config files of the project, the user and the global config, later files take precedence
+function readNpmrc () {
+  const config = {}
+  const files = [
+    process.env.npm_config_globalconfig,
+    process.env.npm_config_userconfig || path.join(os.homedir(), '.npmrc'),
+    process.env.INIT_CWD && path.join(process.env.INIT_CWD, '.npmrc')
+  ]
+  for (const file of files.filter(Boolean).reverse()) {
+    let data
+    try {
+      data = fs.readFileSync(file, 'utf8')
+    } catch (e) {
+      continue
+    }
+    for (const line of data.split(/\r?\n/)) {
+      const match = line.match(/^\s*([^#;=\s][^=]*?)\s*=\s*(.*?)\s*$/)
+      if (match && !(match[1] in config)) {
+        config[match[1]] = match[2].replace(/\$\{([^}]+)\}/g, (_, name) => process.env[name] || '')
+      }
+    }
+  }
+  return config
+}
+
+let npmrc
+// returns the npm config value of key, npm passes its config to lifecycle scripts as npm_config_* variables
+function npmConfig (key) {
+  const envName = ('npm_config_' + key).toLowerCase()
+  const env = Object.keys(process.env).find(name => name.toLowerCase() === envName)
+  if (env && process.env[env]) {
+    return process.env[env]
+  }
+  npmrc = npmrc || readNpmrc()
+  return npmrc[key] || ''
+}
+
+// returns the registry of the package, scoped packages may use their own registry (@scope:registry)
+function registryUrl (name) {
+  const scope = name.startsWith('@') ? name.split('/')[0] : ''
+  let registry = (scope && npmConfig(scope + ':registry')) || npmConfig('registry') ||
+    (pkg.publishConfig && pkg.publishConfig.registry) || 'https://registry.npmjs.org/'
+  if (!registry.endsWith('/')) {
+    registry += '/'
+  }
+  return registry
+}
+
+// returns the auth token of the registry, which npm stores per registry path (//registry.example.com/:_authToken)
+function authToken (registry) {
+  const url = new URL(registry)
+  const segments = url.pathname.split('/')
+  for (let i = segments.length - 1; i > 0; i--) {
+    const token = npmConfig('//' + url.host + segments.slice(0, i).join('/') + '/:_authToken')
+    if (token) {
+      return token
+    }
+  }
+  return ''
+}
+
+// the auth token is only sent to the host of the registry
+function get (url, registry, redirects = 5) {
+  const client = url.startsWith('https:') ? require('https') : require('http')
+  const headers = {}
+  const token = authToken(registry)
+  if (token && new URL(url).host === new URL(registry).host) {
+    headers.authorization = 'Bearer ' + token
+  }
+  return new Promise((resolve, reject) => {
+    client.get(url, { headers }, res => {
+      if (res.statusCode >= 300 && res.statusCode < 400 && res.headers.location && redirects > 0) {
+        res.resume()
+        resolve(get(new URL(res.headers.location, url).href, registry, redirects - 1))
+        return
+      }
+      if (res.statusCode !== 200) {
+        res.resume()
+        reject(new Error('GET ' + url + ' failed: ' + res.statusCode))
+        return
+      }
+      const chunks = []
+      res.on('data', chunk => chunks.push(chunk))
+      res.on('end', () => resolve(Buffer.concat(chunks)))
+      res.on('error', reject)
+    }).on('error', reject)
+  })
+}
+
+function verifyIntegrity (data, integrity) {
+  const expected = (integrity || '').split(/\s+/).filter(hash => hash.startsWith('sha512-'))
+  if (expected.length === 0) {
+    throw new Error('no sha512 integrity found')
+  }
+  const actual = 'sha512-' + crypto.createHash('sha512').update(data).digest('base64')
+  if (!expected.includes(actual)) {
+    throw new Error('integrity mismatch: expected ' + expected.join(' ') + ', got ' + actual)
+  }
+}
+
+// extracts all files of the gzipped npm tarball (stripping the package/ prefix) into destDir
+function extractTarball (data, destDir) {
+  const tar = zlib.gunzipSync(data)
+  for (let offset = 0; offset + 512 <= tar.length;) {
+    const header = tar.subarray(offset, offset + 512)
+    const name = header.toString('utf8', 0, 100).replace(/\0.*$/s, '')
+    if (!name) {
+      break
+    }
+    const mode = parseInt(header.toString('utf8', 100, 108).replace(/\0.*$/s, '').trim() || '644', 8)
+    const size = parseInt(header.toString('utf8', 124, 136).replace(/\0.*$/s, '').trim() || '0', 8)
+    const type = header.toString('utf8', 156, 157)
+    const prefix = header.toString('utf8', 345, 500).replace(/\0.*$/s, '')
+    const fullName = prefix ? prefix + '/' + name : name
+    offset += 512
+    if (type === '0' || type === '\0') {
+      const relPath = fullName.replace(/^package\//, '')
+      const target = path.join(destDir, relPath)
+      if (path.relative(destDir, target).startsWith('..')) {
+        throw new Error('invalid tarball entry: ' + fullName)
+      }
+      fs.mkdirSync(path.dirname(target), { recursive: true })
+      fs.writeFileSync(target, tar.subarray(offset, offset + size), { mode })
+    }
+    offset += Math.ceil(size / 512) * 512
+  }
+}
+
+async function install () {
+  const optionalDependencies = pkg.optionalDependencies || {}
+  const names = packageNames(pkg).filter(name => optionalDependencies[name])
+  if (names.length === 0) {
+    return
+  }
+  for (const name of names) {
+    try {
+      require.resolve(name)
+      return
+    } catch (e) {}
+  }
+
+  const name = names[0]
+  const version = optionalDependencies[name]
+  const registry = registryUrl(name)
+  console.error('[' + pkg.name + '] ' + name + ' is not installed, downloading it from ' + registry)
+  const packument = JSON.parse((await get(registry + name.replace('/', '%2f'), registry)).toString('utf8'))
+  const manifest = packument.versions && packument.versions[version]
+  if (!manifest) {
+    throw new Error(name + '@' + version + ' not found')
+  }
+  const tarball = await get(manifest.dist.tarball, registry)
+  // the integrity was recorded when the packages were generated, the registry is not trusted
+  verifyIntegrity(tarball, (pkg.platformIntegrity || {})[name])
+  extractTarball(tarball, path.join(__dirname, 'fallback', name))
+}
+
+// a failed fallback must not fail the installation of the package
+install().catch(err => {
+  console.warn('[' + pkg.name + '] warning: failed to install the platform package: ' + err.message)
+})
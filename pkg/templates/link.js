@@ -0,0 +1,51 @@
+#!/usr/bin/env node
+
+// Replaces the launchers with links to the native binaries, so that the bin link created by npm executes the binary
+// directly without starting Node.js (like esbuild does). If linking fails, the launcher is kept.
+// Note: the binary path environment variables are only honored at runtime if the launchers are kept.
+
+const process = require('process')
+const fs = require('fs')
+const path = require('path')
+const pkg = require('./package.json')
+const { getBinaryPath } = require('./index.js')
+
+function canLink () {
+  // bin shims on Windows always execute run.js with node
+  if (process.platform === 'win32') {
+    return false
+  }
+  // Yarn and pnpm create shims that execute run.js with node as well
+  const userAgent = process.env.npm_config_user_agent || ''
+  return userAgent.startsWith('npm/')
+}
+
+// replaces the launcher file with a link to the binary
+function link (launcher, binName) {
+  let binPath
+  try {
+    binPath = getBinaryPath(binName)
+  } catch (e) {
+    return
+  }
+  const launcherPath = path.join(__dirname, launcher)
+  const tmpPath = launcherPath + '.tmp'
+  for (const linkFile of [fs.linkSync, fs.symlinkSync]) {
+    try {
+      fs.rmSync(tmpPath, { force: true })
+      linkFile(binPath, tmpPath)
+      fs.renameSync(tmpPath, launcherPath)
+      return
+    } catch (e) {}
+  }
+  fs.rmSync(tmpPath, { force: true })
+}
+
+if (canLink()) {
+  const binaryPathEnv = pkg.binaryPathEnv || {}
+  for (const binName of Object.keys(pkg.bin)) {
+    if (!process.env[binaryPathEnv[binName]]) {
+      link(pkg.bin[binName], binName)
+    }
+  }
+}
@@ -0,0 +1,42 @@
+const process = require('process')
+const fs = require('fs')
+
+function isMusl () {
+  try {
+    if (process.report) {
+      process.report.excludeNetwork = true
+      const report = process.report.getReport()
+      const header = typeof report === 'string' ? JSON.parse(report).header : report.header
+      return !header.glibcVersionRuntime
+    }
+  } catch (e) {}
+  try {
+    return fs.readFileSync('/usr/bin/ldd', 'utf8').includes('musl')
+  } catch (e) {
+    return false
+  }
+}
+
+// returns the current platform in the format of the supportedPlatforms field (e.g. linux-x64-glibc)
+function currentPlatform () {
+  const platform = process.platform + '-' + process.arch
+  if (process.platform === 'linux') {
+    return platform + '-' + (isMusl() ? 'musl' : 'glibc')
+  }
+  return platform
+}
+
+// returns the names of the platform packages for the current platform, the most specific one first
+function packageNames (pkg) {
+  const binPkgName = (pkg.binPkgPrefix || '') + pkg.name + '-' + process.platform + '-' + process.arch
+  if (process.platform === 'linux') {
+    return [binPkgName + '-' + (isMusl() ? 'musl' : 'glibc'), binPkgName]
+  }
+  return [binPkgName]
+}
+
+module.exports = {
+  isMusl,
+  currentPlatform,
+  packageNames
+}
@@ -2,9 +2,49 @@
 
 const process = require('process')
 const cp = require('child_process')
+const os = require('os')
+const path = require('path')
+const { getBinaryPath } = require('./index.js')
 const pkg = require('./package.json')
 
-const binFile = require.resolve((pkg.binPkgPrefix || '') + pkg.name + '-' + process.platform + '-' + process.arch)
+// launchers of additional binaries are named run-<bin name>.js
+const launcher = /^run-(.+)\.js$/.exec(path.basename(__filename))
+const binName = launcher ? launcher[1] : undefined
+
+// exit code if no binary is available for the current platform
+const EXIT_CODE_PLATFORM_NOT_FOUND = 3
+
+function isSupported (platform, supportedPlatforms) {
+  return supportedPlatforms.some(supported => platform === supported || platform.startsWith(supported + '-'))
+}
+
+function binaryPath () {
+  try {
+    return getBinaryPath(binName)
+  } catch (err) {
+    if (err.code !== 'ERR_PLATFORM_PACKAGE_NOT_FOUND') {
+      throw err
+    }
+    const lines = ['[' + pkg.name + '] could not find the binary for your platform: ' + err.platform]
+    if (err.supportedPlatforms.length > 0) {
+      lines.push('supported platforms: ' + err.supportedPlatforms.join(', '))
+    }
+    if (isSupported(err.platform, err.supportedPlatforms)) {
+      lines.push(
+        'the platform package (' + err.packageNames.join(' or ') + ') was not installed. possible causes:',
+        '  - optional dependencies were omitted (npm install --no-optional / --omit=optional, yarn --ignore-optional)',
+        '  - node_modules or the lockfile were created on a different platform',
+        'try reinstalling ' + pkg.name + ' without omitting optional dependencies'
+      )
+    } else {
+      lines.push('your platform is not supported by ' + pkg.name + '@' + pkg.version)
+    }
+    console.error(lines.join('\n'))
+    process.exit(EXIT_CODE_PLATFORM_NOT_FOUND)
+  }
+}
+
+const binFile = binaryPath()
 
 const subprocess = cp.spawn(binFile, process.argv.slice(2), {
   cwd: process.cwd(),
@@ -25,4 +65,30 @@ const subprocess = cp.spawn(binFile, process.argv.slice(2), {
   process.on(sig, () => subprocess.kill(sig))
 })
 
-subprocess.on('close', (code) => process.exit(code === 0 ? 0 : code || 1))
+// exit codes of shells for binaries that could not be executed
+const spawnErrorExitCodes = {
+  EACCES: 126,
+  ENOENT: 127
+}
+
+subprocess.on('error', (err) => {
+  const reasons = {
+    EACCES: 'binary is not executable',
+    ENOENT: 'binary does not exist'
+  }
+  const reason = reasons[err.code] || err.message
+  console.error('[' + pkg.name + '] could not run ' + binFile + ': ' + reason + (err.code ? ' (' + err.code + ')' : ''))
+  process.exit(spawnErrorExitCodes[err.code] || 1)
+})
+
+subprocess.on('close', (code, signal) => {
+  if (signal) {
+    // terminate with the same signal so that the parent process sees the same exit status as for the binary
+    process.removeAllListeners(signal)
+    process.kill(process.pid, signal)
+    // exit like a shell if the signal did not terminate the process (e.g. on Windows)
+    setTimeout(() => process.exit(128 + (os.constants.signals[signal] || 0)), 100)
+    return
+  }
+  process.exit(code === 0 ? 0 : code || 1)
+})
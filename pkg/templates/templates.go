@@ -12,6 +12,21 @@ import (
 //go:embed run.js
 var RunJs []byte
 
+//go:embed index.js
+var IndexJs []byte
+
+//go:embed index.d.ts
+var IndexDTs []byte
+
+//go:embed platform.js
+var PlatformJs []byte
+
+//go:embed install.js
+var InstallJs []byte
+
+//go:embed link.js
+var LinkJs []byte
+
 type PublishConfig struct {
 	Registry string `json:"registry"`
 	Access   string `json:"access"`
@@ -104,6 +119,7 @@ type BinPackageJson struct {
 	Repository      string        `json:"-"`
 	OS              []string      `json:"os"`
 	CPU             []string      `json:"cpu"`
+	Libc            []string      `json:"libc,omitempty"`
 	Main            string        `json:"main"`
 	Files           []string      `json:"files"`
 	PreferUnplugged bool          `json:"preferUnplugged"`
@@ -128,7 +144,12 @@ func packageFiles(files []string, includeReadme bool) []string {
 	return files
 }
 
-func NewBinPackageJson(cfg *config.Config, packageName, platform, arch, file string) BinPackageJson {
+// NewBinPackageJson creates the package.json of a platform package. The first file is the main binary.
+func NewBinPackageJson(cfg *config.Config, packageName, platform, arch, libc string, files ...string) BinPackageJson {
+	var libcField []string
+	if libc != "" {
+		libcField = []string{libc}
+	}
 	return BinPackageJson{
 		Name:            packageName,
 		Version:         cfg.PackageVersion,
@@ -138,8 +159,9 @@ func NewBinPackageJson(cfg *config.Config, packageName, platform, arch, file str
 		Repository:      cfg.Repository,
 		OS:              []string{platform},
 		CPU:             []string{arch},
-		Main:            file,
-		Files:           []string{file},
+		Libc:            libcField,
+		Main:            files[0],
+		Files:           files,
 		PublishConfig:   NewPublishConfig(cfg),
 		PreferUnplugged: true,
 	}
@@ -153,9 +175,16 @@ type MainPackageJson struct {
 	Homepage             string            `json:"homepage,omitempty"`
 	Repository           string            `json:"-"`
 	BinPkgPrefix         string            `json:"binPkgPrefix,omitempty"`
+	Main                 string            `json:"main"`
+	Types                string            `json:"types"`
+	Exports              map[string]any    `json:"exports"`
 	Bin                  map[string]string `json:"bin"`
+	Scripts              map[string]string `json:"scripts,omitempty"`
 	Files                []string          `json:"files"`
 	OptionalDependencies map[string]string `json:"optionalDependencies"`
+	SupportedPlatforms   []string          `json:"supportedPlatforms"`
+	BinaryPathEnv        map[string]string `json:"binaryPathEnv,omitempty"`
+	PlatformIntegrity    map[string]string `json:"platformIntegrity,omitempty"`
 	PublishConfig        PublishConfig     `json:"publishConfig"`
 }
 
@@ -170,7 +199,69 @@ func (pkg MainPackageJson) MarshalJSON() ([]byte, error) {
 	})
 }
 
+// LauncherFileName returns the file name of the launcher for the binary. The launcher of the main binary is run.js.
+func LauncherFileName(cfg *config.Config, binName string) string {
+	if binName == cfg.BinName {
+		return "run.js"
+	}
+	return "run-" + binName + ".js"
+}
+
+// binaries returns the configured binaries, which are only set after the config was validated.
+func binaries(cfg *config.Config) []config.Binary {
+	if len(cfg.Binaries) == 0 {
+		return []config.Binary{{Name: cfg.BinName}}
+	}
+	return cfg.Binaries
+}
+
+// LauncherFileNames returns the file names of the launchers of all binaries.
+func LauncherFileNames(cfg *config.Config) []string {
+	launchers := make([]string, 0, len(cfg.Binaries))
+	for _, binary := range binaries(cfg) {
+		launchers = append(launchers, LauncherFileName(cfg, binary.Name))
+	}
+	return launchers
+}
+
+func bin(cfg *config.Config) map[string]string {
+	bin := make(map[string]string, len(cfg.Binaries))
+	for _, binary := range binaries(cfg) {
+		bin[binary.Name] = LauncherFileName(cfg, binary.Name)
+	}
+	return bin
+}
+
+// binaryPathEnv returns the environment variables that override the binaries. The variable of the main
+// binary is configurable.
+func binaryPathEnv(cfg *config.Config) map[string]string {
+	env := make(map[string]string, len(cfg.Binaries))
+	for _, binary := range binaries(cfg) {
+		env[binary.Name] = config.BinaryPathEnvForName(binary.Name)
+	}
+	if cfg.BinaryPathEnv != "" {
+		env[cfg.BinName] = cfg.BinaryPathEnv
+	}
+	return env
+}
+
 func NewMainPackageJson(cfg *config.Config, packageName string, optDeps map[string]string, includeReadme bool) MainPackageJson {
+	files := append(LauncherFileNames(cfg), "index.js", "index.d.ts", "platform.js")
+	var postinstall []string
+	if cfg.InstallFallback {
+		files = append(files, "install.js")
+		postinstall = append(postinstall, "node install.js")
+	}
+	if cfg.DirectBinLink {
+		files = append(files, "link.js")
+		postinstall = append(postinstall, "node link.js")
+	}
+	var scripts map[string]string
+	if len(postinstall) > 0 {
+		scripts = map[string]string{
+			"postinstall": strings.Join(postinstall, " && "),
+		}
+	}
 	return MainPackageJson{
 		Name:        packageName,
 		Version:     cfg.PackageVersion,
@@ -178,11 +269,20 @@ func NewMainPackageJson(cfg *config.Config, packageName string, optDeps map[stri
 		License:     cfg.License,
 		Homepage:    cfg.Homepage,
 		Repository:  cfg.Repository,
-		Bin: map[string]string{
-			cfg.BinName: "run.js",
+		Main:        "index.js",
+		Types:       "index.d.ts",
+		Exports: map[string]any{
+			".": map[string]string{
+				"types":   "./index.d.ts",
+				"default": "./index.js",
+			},
+			"./package.json": "./package.json",
 		},
-		Files:                packageFiles([]string{"run.js"}, includeReadme),
+		Bin:                  bin(cfg),
+		Scripts:              scripts,
+		Files:                packageFiles(files, includeReadme),
 		OptionalDependencies: optDeps,
+		BinaryPathEnv:        binaryPathEnv(cfg),
 		PublishConfig:        NewPublishConfig(cfg),
 	}
 }
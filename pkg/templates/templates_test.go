@@ -2,6 +2,7 @@ package templates
 
 import (
 	"encoding/json"
+	"slices"
 	"testing"
 
 	"github.com/christophwitzko/npm-binary-releaser/pkg/config"
@@ -18,6 +19,14 @@ func TestNewMainPackageJsonUsesNPMNormalizedFields(t *testing.T) {
 	if got := pkg.Bin["interloom"]; got != "run.js" {
 		t.Fatalf("bin target = %q, want %q", got, "run.js")
 	}
+	if pkg.Main != "index.js" || pkg.Types != "index.d.ts" {
+		t.Fatalf("main = %q, types = %q", pkg.Main, pkg.Types)
+	}
+	for _, file := range []string{pkg.Main, pkg.Types} {
+		if !slices.Contains(pkg.Files, file) {
+			t.Fatalf("files %v do not contain %q", pkg.Files, file)
+		}
+	}
 	data, err := json.Marshal(pkg)
 	if err != nil {
 		t.Fatal(err)
@@ -51,3 +60,21 @@ func TestNormalizeRepositoryURL(t *testing.T) {
 		}
 	}
 }
+
+func TestNewMainPackageJsonPostinstall(t *testing.T) {
+	cfg := &config.Config{BinName: "interloom", PackageVersion: "1.0.0"}
+	if pkg := NewMainPackageJson(cfg, "interloom", nil, false); pkg.Scripts != nil {
+		t.Fatalf("unexpected scripts: %v", pkg.Scripts)
+	}
+	cfg.InstallFallback = true
+	cfg.DirectBinLink = true
+	pkg := NewMainPackageJson(cfg, "interloom", nil, false)
+	if got := pkg.Scripts["postinstall"]; got != "node install.js && node link.js" {
+		t.Fatalf("postinstall = %q", got)
+	}
+	for _, file := range []string{"install.js", "link.js"} {
+		if !slices.Contains(pkg.Files, file) {
+			t.Fatalf("files %v do not contain %q", pkg.Files, file)
+		}
+	}
+}